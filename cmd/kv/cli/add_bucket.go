@@ -25,8 +25,9 @@ Arguments:
 	Example: `
   kv add bucket prod-secrets
   kv add bucket prod-secret --generate-new-key # for create a bucket with a separate encryption key`,
-	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:        cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Annotations: map[string]string{annotationNoAgent: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		bucket := args[0]
 
 		s := storage.NewEntityStorage(kvdb, "")
@@ -34,44 +35,40 @@ Arguments:
 		// Check bucket exist
 		if exist, err := s.BucketExist(bucket); err != nil || exist {
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				return err
 			}
-			fmt.Printf("bucket %q exist\n", bucket)
-			os.Exit(1)
+			return fmt.Errorf("bucket %q exist", bucket)
 		}
 
 		// Create a new bucket
 		if err := s.AddBucket(bucket); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
 
 		// if generate-mew-key is true
 		// generate and save new encryption key to the encryption store
 		if generateNewKey {
 			if encryptionKenStore.HasKey(bucket) {
-				fmt.Printf("Encryption key for the bucket %s is exist", bucket)
+				fmt.Fprintf(os.Stderr, "Encryption key for the bucket %s is exist\n", bucket)
 			}
 
 			enckey, err := enckeystore.GenerateEncryptionKey()
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				return err
 			}
 
 			if err := encryptionKenStore.AddKey(bucket, enckeystore.EncryptionKey(enckey)); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				return err
 			}
 
 			if err := encryptionKenStore.Save(); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				return err
 			}
 		}
 
-		fmt.Printf("add bucket: %s successfully\n", bucket)
+		return printResult(map[string]any{"bucket": bucket, "new_key": generateNewKey}, func() {
+			fmt.Printf("add bucket: %s successfully\n", bucket)
+		})
 	},
 }
 
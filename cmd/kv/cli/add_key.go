@@ -1,16 +1,26 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	addKeyDescription string
+	addKeyTags        []string
+	addKeyTTL         string
+	addKeyExpiresAt   string
+)
+
 // addKeyCmd represents the add key command
 var addKeyCmd = &cobra.Command{
 	Use:   "key",
@@ -23,7 +33,13 @@ Arguments:
 
   <key>         The key to store the value under in the default bucket.
   <key@bucket>  The key to store in a specific named bucket.
-  <value>       The value to be encrypted and stored.`,
+  <value>       The value to be encrypted and stored.
+
+A description and tags are stored unencrypted next to the value.
+When a key is updated without --description or --tag, the existing ones are kept.
+
+Use --ttl or --expires-at to set the value expiry time. An expired key can't be read,
+and "kv gc" removes expired keys. Updating a key without --ttl or --expires-at clears the expiry.`,
 	Example: `
   kv add key username admin
   kv add key --bucket=prod username admin
@@ -32,33 +48,70 @@ Arguments:
   # read value from file
   kv add key longtext @readme.txt
   # read value from STDIN
-  echo 'env=prod' | kv add key config@env @-`,
+  echo 'env=prod' | kv add key config@env @-
+  # add description and tags
+  kv add key token@prod abc --description "payments api token" --tag team=payments --tag owner=ops
+  # set expiry time
+  kv add key ci-token@ci abc --ttl 720h
+  kv add key ci-token@ci abc --ttl 30d
+  kv add key ci-token@ci abc --expires-at 2026-12-31T23:59:59Z`,
 	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		k, b := parseKey(args[0])
 
-		encKey, err := selectKey(encryptionKeys, b)
+		s, err := bucketStorage(b)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
 
-		s := storage.NewEntityStorage(kvdb, encKey)
 		val, err := readValue(args[1])
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "add key: %s failed: %s\n", k, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("add key: %s failed: %w", k, err)
 		}
-		err = s.Add(b, k, val)
+		tags, err := utils.ParseTags(addKeyTags)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "add key: %s failed: %s\n", k, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("add key: %s failed: %w", k, err)
+		}
+		expiresAt, err := parseExpiry(addKeyTTL, addKeyExpiresAt)
+		if err != nil {
+			return fmt.Errorf("add key: %s failed: %w", k, err)
+		}
+		err = s.AddWithMetadata(b, k, val, models.Metadata{Description: addKeyDescription, Tags: tags, ExpiresAt: expiresAt})
+		if err != nil {
+			return fmt.Errorf("add key: %s failed: %w", k, err)
 		}
 
-		fmt.Printf("add key: %s successfully\n", k)
+		return printResult(map[string]string{"bucket": b, "key": k}, func() {
+			fmt.Printf("add key: %s successfully\n", k)
+		})
 	},
 }
 
+// parseExpiry returns the expiry time from --ttl or --expires-at values.
+// Zero time means no expiry.
+func parseExpiry(ttl, expiresAt string) (time.Time, error) {
+	switch {
+	case ttl != "" && expiresAt != "":
+		return time.Time{}, errors.New("--ttl and --expires-at can't be used together")
+	case ttl != "":
+		d, err := utils.ParseDuration(ttl)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("ttl must be positive, got %q", ttl)
+		}
+		return time.Now().Add(d).UTC(), nil
+	case expiresAt != "":
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid expiry time %q, expected RFC3339 (e.g. 2026-12-31T23:59:59Z)", expiresAt)
+		}
+		return t.UTC(), nil
+	}
+	return time.Time{}, nil
+}
+
 // readValue interprets a value argument, supporting:
 // plain string
 // @filename to read content from a file
@@ -83,4 +136,9 @@ func readValue(arg string) (string, error) {
 
 func init() {
 	addCmd.AddCommand(addKeyCmd)
+
+	addKeyCmd.Flags().StringVar(&addKeyDescription, "description", "", "key description")
+	addKeyCmd.Flags().StringArrayVar(&addKeyTags, "tag", nil, "key tag in key=value form, can be repeated")
+	addKeyCmd.Flags().StringVar(&addKeyTTL, "ttl", "", "value time to live, e.g. 720h, 30d, 2w")
+	addKeyCmd.Flags().StringVar(&addKeyExpiresAt, "expires-at", "", "value expiry time in RFC3339 format")
 }
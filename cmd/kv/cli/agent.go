@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run a background agent that holds the unlocked encryption keys.",
+	Long: `The agent loads and unlocks the encryption key store once and keeps the keys in memory,
+so kv commands don't read the key store or ask for the passphrase on every run.
+
+Like ssh-agent, "kv agent start" prints the KV_AGENT_SOCK variable for the shell. Commands
+find the agent with KV_AGENT_SOCK and send values to it to be encrypted or decrypted,
+the keys never leave the agent. Without a running agent the key store is read as usual.
+
+The keys are kept in locked memory where possible and wiped when the agent stops:
+on "kv agent stop", after --idle-timeout without requests, or when the key store
+file is changed (kv keys rotate, kv keys passwd, kv add bucket --generate-new-key).
+Commands that change the key store always read it and don't use the agent.`,
+	Example: `
+  eval "$(kv agent start)"
+  kv get token@prod
+  kv agent status
+  kv agent stop`,
+	Args: cobra.NoArgs,
+}
+
+// agentSocket returns the agent socket path from KV_AGENT_SOCK.
+func agentSocket() (string, error) {
+	sock := os.Getenv("KV_AGENT_SOCK")
+	if sock == "" {
+		return "", errors.New("agent is not running: KV_AGENT_SOCK is not set")
+	}
+	return sock, nil
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cli
+
+import "syscall"
+
+// detachedProcess returns attributes of the agent process.
+func detachedProcess() *syscall.SysProcAttr {
+	return nil
+}
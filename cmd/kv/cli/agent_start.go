@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/yousysadmin/kv/internal/agent"
+	"github.com/yousysadmin/kv/internal/server"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+var (
+	agentSocketPath  string
+	agentIdleTimeout time.Duration
+	agentForeground  bool
+)
+
+// agentStartCmd represents the agent start command
+var agentStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the agent.",
+	Long: `This command loads and unlocks the encryption key store and starts the agent in the background.
+The passphrase of a protected key store is read from KV_PASSPHRASE, --passphrase-file or asked interactively.
+
+It prints shell commands that set KV_AGENT_SOCK, evaluate them to use the agent in the current shell.
+The socket is created with 0600 permissions at --socket, KV_AGENT_SOCK or ~/.kv-agent.sock.
+
+With --foreground the agent runs in the current process until it is stopped or interrupted.`,
+	Example: `
+  eval "$(kv agent start)"
+  eval "$(kv agent start --idle-timeout 8h)"
+  kv agent start --foreground --socket /run/user/1000/kv-agent.sock`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoAgent: "true", annotationNoDatabase: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if encryptionKenStore == nil {
+			return errors.New("agent start: failed: encryption key store is not used (--encryption-key is set)")
+		}
+		sock, err := filepath.Abs(agentSocketPath)
+		if err != nil {
+			return fmt.Errorf("agent start: failed: %w", err)
+		}
+		store, err := filepath.Abs(viper.GetString("encryption-key-store"))
+		if err != nil {
+			return fmt.Errorf("agent start: failed: %w", err)
+		}
+
+		if agentForeground {
+			var buf bytes.Buffer
+			if err := agent.EncodeKeys(&buf, encryptionKeys); err != nil {
+				return fmt.Errorf("agent start: failed: %w", err)
+			}
+			encoded := buf.Bytes()
+			a, err := agent.New(store, &buf, agentIdleTimeout)
+			clear(encoded)
+			if err != nil {
+				return fmt.Errorf("agent start: failed: %w", err)
+			}
+			return runAgent(a, sock, func() {
+				printAgentEnv(sock, os.Getpid())
+			})
+		}
+
+		pid, err := spawnAgent(sock, store)
+		if err != nil {
+			return fmt.Errorf("agent start: failed: %w", err)
+		}
+		printAgentEnv(sock, pid)
+		return nil
+	},
+}
+
+// agentRunCmd runs the agent started in the background by agent start,
+// the keys are read from STDIN (see agent.EncodeKeys) and the readiness is reported to STDOUT.
+var agentRunCmd = &cobra.Command{
+	Use:         "run",
+	Hidden:      true,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoKeys: "true", annotationNoDatabase: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := agent.New(viper.GetString("encryption-key-store"), os.Stdin, agentIdleTimeout)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		err = runAgent(a, agentSocketPath, func() {
+			fmt.Println("ok")
+			os.Stdout.Close()
+		})
+		if err != nil {
+			// STDOUT is still open if the agent failed to start
+			fmt.Println(err)
+		}
+		return err
+	},
+}
+
+// runAgent serves the agent on a unix socket until it is stopped or interrupted,
+// ready is called when the agent accepts connections.
+func runAgent(a *agent.Agent, sock string, ready func()) error {
+	l, err := server.Listen("unix://" + sock)
+	if err != nil {
+		return fmt.Errorf("agent: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		a.Stop()
+	}()
+
+	ready()
+	if err := a.Serve(l); err != nil {
+		return fmt.Errorf("agent: %w", err)
+	}
+	return nil
+}
+
+// spawnAgent starts the agent in a detached process and passes the keys to it.
+// It returns the agent process id when the agent accepts connections.
+func spawnAgent(sock, store string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	c := exec.Command(exe, "agent", "run",
+		"--socket", sock,
+		"--idle-timeout", agentIdleTimeout.String(),
+		"--encryption-key-store-path", store,
+	)
+	c.SysProcAttr = detachedProcess()
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+
+	err = agent.EncodeKeys(stdin, encryptionKeys)
+	stdin.Close()
+	if err != nil {
+		_ = c.Process.Kill()
+		return 0, fmt.Errorf("pass keys to the agent: %w", err)
+	}
+
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	if line = strings.TrimSpace(line); line != "ok" {
+		_ = c.Wait()
+		if line == "" {
+			line = "agent exited"
+		}
+		return 0, errors.New(line)
+	}
+	pid := c.Process.Pid
+	return pid, c.Process.Release()
+}
+
+// printAgentEnv prints shell commands that set KV_AGENT_SOCK, or the agent as JSON with --output json.
+func printAgentEnv(sock string, pid int) {
+	_ = printResult(map[string]any{"socket": sock, "pid": pid}, func() {
+		fmt.Printf("KV_AGENT_SOCK=%s; export KV_AGENT_SOCK;\n", utils.QuotePOSIX(sock))
+		fmt.Printf("echo Agent pid %d;\n", pid)
+	})
+}
+
+func init() {
+	agentCmd.AddCommand(agentStartCmd)
+	agentCmd.AddCommand(agentRunCmd)
+
+	defaultSocket := os.Getenv("KV_AGENT_SOCK")
+	if defaultSocket == "" {
+		defaultSocket = expandPath("~/.kv-agent.sock")
+	}
+	for _, c := range []*cobra.Command{agentStartCmd, agentRunCmd} {
+		c.Flags().StringVar(&agentSocketPath, "socket", defaultSocket, "agent socket path (can also use KV_AGENT_SOCK)")
+		c.Flags().DurationVar(&agentIdleTimeout, "idle-timeout", time.Hour, "stop the agent after this time without requests, 0 runs until stopped")
+	}
+	agentStartCmd.Flags().BoolVar(&agentForeground, "foreground", false, "run the agent in the current process")
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yousysadmin/kv/internal/agent"
+)
+
+// agentStatusCmd represents the agent status command
+var agentStatusCmd = &cobra.Command{
+	Use:         "status",
+	Short:       "Show the agent status.",
+	Long:        `This command shows the status of the agent at KV_AGENT_SOCK.`,
+	Example:     "\n  kv agent status",
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoKeys: "true", annotationNoDatabase: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sock, err := agentSocket()
+		if err != nil {
+			return fmt.Errorf("agent status: failed: %w", err)
+		}
+		c, err := agent.Dial(sock)
+		if err != nil {
+			return fmt.Errorf("agent status: failed: agent is not running: %w", err)
+		}
+		defer c.Close()
+		st, err := c.Status()
+		if err != nil {
+			return fmt.Errorf("agent status: failed: %w", err)
+		}
+		return printResult(map[string]any{"socket": sock, "status": st}, func() {
+			fmt.Printf("Socket:        %s\n", sock)
+			fmt.Printf("PID:           %d\n", st.PID)
+			fmt.Printf("Key store:     %s\n", st.KeyStore)
+			fmt.Printf("Buckets:       %s\n", strings.Join(st.Buckets, ", "))
+			fmt.Printf("Memory locked: %t\n", st.MemoryLocked)
+			fmt.Printf("Idle timeout:  %s\n", st.IdleTimeout)
+			fmt.Printf("Started at:    %s\n", st.StartedAt.Format(time.RFC3339))
+		})
+	},
+}
+
+func init() {
+	agentCmd.AddCommand(agentStatusCmd)
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yousysadmin/kv/internal/agent"
+)
+
+// agentStopCmd represents the agent stop command
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the agent.",
+	Long: `This command stops the agent at KV_AGENT_SOCK, the agent wipes the keys from memory.
+Unset KV_AGENT_SOCK afterwards, e.g. with: eval "$(kv agent stop)"`,
+	Example: `
+  kv agent stop
+  eval "$(kv agent stop)"`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoKeys: "true", annotationNoDatabase: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sock, err := agentSocket()
+		if err != nil {
+			return fmt.Errorf("agent stop: failed: %w", err)
+		}
+		c, err := agent.Dial(sock)
+		if err != nil {
+			return fmt.Errorf("agent stop: failed: agent is not running: %w", err)
+		}
+		defer c.Close()
+		if err := c.Stop(); err != nil {
+			return fmt.Errorf("agent stop: failed: %w", err)
+		}
+		return printResult(map[string]any{"socket": sock, "stopped": true}, func() {
+			fmt.Println("unset KV_AGENT_SOCK;")
+			fmt.Println("echo Agent stopped;")
+		})
+	},
+}
+
+func init() {
+	agentCmd.AddCommand(agentStopCmd)
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package cli
+
+import "syscall"
+
+// detachedProcess returns attributes of the agent process, it runs in its own session
+// and isn't stopped with the terminal.
+func detachedProcess() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
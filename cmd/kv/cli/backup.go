@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"github.com/yousysadmin/kv/internal/backup"
+	"github.com/yousysadmin/kv/internal/utils"
+	"github.com/yousysadmin/kv/pkg"
+)
+
+var (
+	backupOutput         string
+	backupIncludeKeys    bool
+	backupPassphraseFile string
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write a backup archive of the database.",
+	Long: `This command writes a consistent snapshot of the database to a backup archive,
+it's safe to run while other kv processes read or write the database.
+
+With --include-keys the encryption key store is added to the archive, sealed with
+a separate backup passphrase read from KV_BACKUP_PASSPHRASE, --backup-passphrase-file
+or asked interactively. Without it, values in the archive can only be read with the
+current encryption keys.
+
+The archive has a manifest with the format version and checksums of all files,
+"kv restore" verifies it before restoring anything.
+Without --output-file the archive is written to kv-<date>.kvbak, "-" writes it to STDOUT.`,
+	Example: `
+  kv backup -o kv-2026-10-17.kvbak
+  kv backup --include-keys -o kv-full.kvbak
+  kv backup -o - | ssh backup-host 'cat > kv.kvbak'`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationReadOnly: "true", annotationNoKeys: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := backup.Options{KVVersion: pkg.Version}
+		if backupIncludeKeys {
+			if viper.GetString("encryption-key") != "" {
+				return errors.New("backup: failed: encryption key store is not used (--encryption-key is set)")
+			}
+			ks, err := os.ReadFile(viper.GetString("encryption-key-store"))
+			if err != nil {
+				return fmt.Errorf("backup: read key store failed: %w", err)
+			}
+			p, err := readBackupPassphrase(true)
+			if err != nil {
+				return fmt.Errorf("backup: failed: %w", err)
+			}
+			opts.KeyStore, opts.Passphrase = ks, p
+		}
+
+		output := backupOutput
+		if output == "" {
+			output = "kv-" + time.Now().Format(time.DateOnly) + ".kvbak"
+		}
+
+		if output == "-" {
+			_, err := backup.Write(os.Stdout, kvdb, opts)
+			if err != nil {
+				return fmt.Errorf("backup: failed: %w", err)
+			}
+			return nil
+		}
+
+		var m *backup.Manifest
+		err := utils.AtomicWrite(output, 0o600, func(w io.Writer) error {
+			var err error
+			m, err = backup.Write(w, kvdb, opts)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("backup: %s failed: %w", output, err)
+		}
+		return printResult(map[string]any{"output": output, "manifest": m}, func() {
+			keys := "without key store"
+			if m.HasKeyStore() {
+				keys = "with key store"
+			}
+			fmt.Printf("backup: %s successfully, %d buckets, %s\n", output, len(m.Buckets), keys)
+		})
+	},
+}
+
+// readBackupPassphrase returns the backup passphrase from the KV_BACKUP_PASSPHRASE environment variable,
+// the --backup-passphrase-file file or an interactive prompt, a new passphrase is asked twice.
+func readBackupPassphrase(confirm bool) ([]byte, error) {
+	if p := os.Getenv("KV_BACKUP_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	if backupPassphraseFile != "" {
+		return readPassphraseFile(backupPassphraseFile)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errors.New("backup passphrase is required: set KV_BACKUP_PASSPHRASE or use --backup-passphrase-file")
+	}
+	p, err := promptPassphrase("Backup passphrase: ")
+	if err != nil || !confirm {
+		return p, err
+	}
+	repeat, err := promptPassphrase("Repeat backup passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, repeat) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.Flags().StringVarP(&backupOutput, "output-file", "o", "", "archive file, - for STDOUT (default kv-<date>.kvbak)")
+	backupCmd.Flags().BoolVarP(&backupIncludeKeys, "include-keys", "k", false, "include the encryption key store sealed with a backup passphrase")
+	backupCmd.Flags().StringVar(&backupPassphraseFile, "backup-passphrase-file", "", "read the backup passphrase from a file (can also use KV_BACKUP_PASSPHRASE)")
+}
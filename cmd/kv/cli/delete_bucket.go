@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/yousysadmin/kv/internal/storage"
 
@@ -19,16 +18,17 @@ This command removes the specified bucket from the store.`,
 	Example: `
   kv delete prod`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		bucket := args[0]
 		s := storage.NewEntityStorage(kvdb, "")
 		err := s.DeleteBucket(bucket)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "delete bucket: %s failed: %s\n", bucket, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("delete bucket: %s failed: %w", bucket, err)
 		}
 
-		fmt.Printf("delete bucket: %s successfully\n", bucket)
+		return printResult(map[string]string{"bucket": bucket}, func() {
+			fmt.Printf("delete bucket: %s successfully\n", bucket)
+		})
 	},
 }
 
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/yousysadmin/kv/internal/storage"
 
@@ -15,23 +14,26 @@ var deleteKeyCmd = &cobra.Command{
 	Short: "Delete a key.",
 	Long: `Delete a key from the store.
 
-This command removes the specified key from store.
-If no bucket is provided, the key will be deleted from the default bucket.`,
+This command removes the specified key and all its versions from store.
+If no bucket is provided, the key will be deleted from the default bucket.
+
+This action is permanent and cannot be undone.`,
 	Example: `
   kv delete username
   kv delete --bucket=prod username
   kv delete token@authservice`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		k, b := parseKey(args[0])
 		s := storage.NewEntityStorage(kvdb, "")
 		err := s.Delete(b, k)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "delete key: %s in bucket %s failed: %s\n", k, b, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("delete key: %s in bucket %s failed: %w", k, b, err)
 		}
 
-		fmt.Printf("delete key: %s in bucket %s successfully\n", k, b)
+		return printResult(map[string]string{"bucket": b, "key": k}, func() {
+			fmt.Printf("delete key: %s in bucket %s successfully\n", k, b)
+		})
 	},
 }
 
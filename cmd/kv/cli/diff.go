@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yousysadmin/kv/internal/diff"
+)
+
+var (
+	diffShowValues bool
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare a bucket with an external store",
+	Long: `The diff command shows keys that differ between a bucket and an external store.
+
+Changes are shown from the bucket to the external store:
+  + key  only in the external store
+  - key  only in the bucket
+  ~ key  in both with different values
+
+Values are hidden unless --values is set.`,
+	Args: cobra.NoArgs,
+}
+
+// printChanges prints key changes, with values if showValues is set.
+func printChanges(changes []diff.Change, showValues bool) {
+	for _, c := range changes {
+		switch {
+		case c.Kind == diff.Added && showValues:
+			fmt.Printf("+ %s => %s\n", c.Key, c.New)
+		case c.Kind == diff.Removed && showValues:
+			fmt.Printf("- %s => %s\n", c.Key, c.Old)
+		case c.Kind == diff.Changed && showValues:
+			fmt.Printf("~ %s => %s (was %s)\n", c.Key, c.New, c.Old)
+		case c.Kind == diff.Added:
+			fmt.Printf("+ %s\n", c.Key)
+		case c.Kind == diff.Removed:
+			fmt.Printf("- %s\n", c.Key)
+		default:
+			fmt.Printf("~ %s\n", c.Key)
+		}
+	}
+}
+
+// changesResult returns changes for the JSON output, without values unless showValues is set.
+func changesResult(changes []diff.Change, showValues bool) []diff.Change {
+	out := make([]diff.Change, 0, len(changes))
+	for _, c := range changes {
+		if !showValues {
+			c.Old, c.New = "", ""
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.PersistentFlags().BoolVarP(&diffShowValues, "values", "v", false, "Show key values")
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+
+	awsSsm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+	"github.com/yousysadmin/kv/internal/diff"
+	"github.com/yousysadmin/kv/internal/importer/amazon"
+	"github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+)
+
+var (
+	diffSsmAws amazon.Flags
+)
+
+// diffSsmCmd represents the diff ssm command
+var diffSsmCmd = &cobra.Command{
+	Use:   "ssm <path>",
+	Short: "Compare a bucket with an AWS SSM Parameter Store path",
+	Long: `Compare keys of the bucket with parameters under the path, a parameter <path>/<key> matches the key <key>.
+
+Changes are shown from the bucket to SSM:
+  + key  only in SSM ("kv sync ssm --direction pull" adds it to the bucket)
+  - key  only in the bucket ("kv sync ssm --direction push" adds it to SSM)
+  ~ key  in both with different values
+
+Expired keys are treated as missing from the bucket.`,
+	Example: `
+  kv diff ssm --bucket=prod /prod/app/
+  kv diff ssm --bucket=prod --values /prod/app/`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+
+		local, err := bucketSecrets(bucketName)
+		if err != nil {
+			return fmt.Errorf("diff with ssm failed: %w", err)
+		}
+
+		ctx := cmd.Context()
+		cfg, err := diffSsmAws.Config(ctx)
+		if err != nil {
+			return fmt.Errorf("diff with ssm failed: %w", err)
+		}
+		remote, err := ssm.GetKeys(ctx, awsSsm.NewFromConfig(cfg), path)
+		if err != nil {
+			return fmt.Errorf("diff with ssm failed: %w", err)
+		}
+
+		changes := diff.Compare(local, remote)
+		return printResult(map[string]any{"changes": changesResult(changes, diffShowValues)}, func() {
+			if len(changes) == 0 {
+				fmt.Println("No differences.")
+				return
+			}
+			printChanges(changes, diffShowValues)
+		})
+	},
+}
+
+func init() {
+	diffCmd.AddCommand(diffSsmCmd)
+
+	diffSsmAws.Register(diffSsmCmd.PersistentFlags())
+}
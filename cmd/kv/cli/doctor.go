@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/yousysadmin/kv/internal/doctor"
+)
+
+var (
+	doctorStrict bool
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Aliases: []string{"fsck"},
+	Short:   "Check the integrity of the database and the encryption key store.",
+	Long: `This command checks the store and reports every problem it finds:
+  database         bbolt consistency check failed
+  bucket-key       bucket has no key in the key store and uses the default key, or its key is invalid
+  orphan-key       key store has a key for a bucket that doesn't exist
+  value-prefix     value was encrypted with a key of another size than the bucket key
+  decrypt          value can't be decrypted with the bucket key, or is in the old format in a migrated database
+  network-storage  database or key store is on a network filesystem
+  permissions      database or key store permissions are looser than 0600
+
+Values are checked with their current version and every version in the key history.
+With --encryption-key the key store checks are skipped and all buckets are checked with the key.
+
+The command exits with code 10 when it finds an error, or a warning with --strict,
+so it can be used in CI. With --json (or --output json) the report is printed as JSON.`,
+	Example: `
+  kv doctor
+  kv fsck --json
+  kv doctor --strict`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationReadOnly: "true", annotationNoAgent: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := doctor.Options{DBPath: viper.GetString("db"), Keys: encryptionKeys}
+		if encryptionKenStore != nil {
+			opts.KeyStorePath = viper.GetString("encryption-key-store")
+		}
+
+		r, err := doctor.Run(kvdb, opts)
+		if err != nil {
+			return fmt.Errorf("doctor: failed: %w", err)
+		}
+
+		err = printResult(r, func() {
+			for _, f := range r.Findings {
+				fmt.Println(f)
+			}
+			fmt.Printf("Checked %d buckets, %d values: %d errors, %d warnings.\n", r.Buckets, r.Values, r.Errors(), r.Warnings())
+		})
+		if err != nil {
+			return err
+		}
+
+		if r.Errors() > 0 || (doctorStrict && r.Warnings() > 0) {
+			return fmt.Errorf("doctor: %w: %d errors, %d warnings", doctor.ErrFailed, r.Errors(), r.Warnings())
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorStrict, "strict", false, "fail on warnings too")
+	doctorCmd.Flags().Bool("json", false, "print the report as JSON, same as --output json")
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	execBuckets  []string
+	execPrefix   string
+	execCleanEnv bool
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command with bucket keys in its environment.",
+	Long: `This command decrypts all keys in one or more buckets and runs the command
+with those keys added to its environment.
+
+Key names are normalized the same way as for dotenv output (uppercase, non-alphanumeric
+characters are replaced with '_'). Two keys of a bucket that become the same name
+are an error. When several buckets are given, they are merged in order and
+a variable from a later bucket overrides the same variable from an earlier one.
+Expired keys are not added.
+
+By default the command inherits the current environment, use --clean-env to start
+with an empty one. Signals are forwarded to the command and its exit code is returned.`,
+	Example: `
+  kv exec --bucket prod-api -- ./server
+  kv exec -b common -b prod-api -- ./server --port 8080
+  kv exec --bucket prod-api --prefix APP_ -- env
+  kv exec --bucket prod-api --clean-env -- /usr/bin/env`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		buckets := execBuckets
+		if len(buckets) == 0 {
+			buckets = []string{bucketName}
+		}
+
+		vars := make(map[string]string)
+		for _, b := range buckets {
+			s, err := bucketStorage(b)
+			if err != nil {
+				return fmt.Errorf("exec: %w", err)
+			}
+
+			v, err := s.List(b, storage.ListOptions{Values: true, SkipExpired: true})
+			if err != nil {
+				return fmt.Errorf("exec: list keys in bucket: `%s` failed: %w", b, err)
+			}
+			bv, err := utils.ToEnvVars(v, execPrefix)
+			if err != nil {
+				return fmt.Errorf("exec: bucket `%s`: %w", b, err)
+			}
+			maps.Copy(vars, bv)
+		}
+
+		// Release the database lock, the command can run for a long time
+		if err := kvdb.Close(); err != nil {
+			return fmt.Errorf("exec: close database: %w", err)
+		}
+
+		var environ []string
+		if !execCleanEnv {
+			environ = os.Environ()
+		}
+
+		c := exec.Command(args[0], args[1:]...)
+		c.Env = utils.MergeEnv(environ, vars)
+		c.Stdin = os.Stdin
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+
+		if err := c.Start(); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, forwardSignals...)
+		go func() {
+			for sig := range sigs {
+				_ = c.Process.Signal(sig)
+			}
+		}()
+
+		err := c.Wait()
+		signal.Stop(sigs)
+		close(sigs)
+
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return fmt.Errorf("exec: %w", err)
+		}
+		// the exit code of the command is returned as is
+		os.Exit(exitCode(c.ProcessState))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().StringSliceVarP(&execBuckets, "bucket", "b", nil, "bucket to load, can be repeated (default: the --bucket/KV_BUCKET value)")
+	execCmd.Flags().StringVarP(&execPrefix, "prefix", "p", "", "prefix added to every variable name")
+	execCmd.Flags().BoolVar(&execCleanEnv, "clean-env", false, "do not inherit the current environment")
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardSignals are passed through to the child process of kv exec.
+var forwardSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
+// exitCode returns the exit code of a finished process.
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
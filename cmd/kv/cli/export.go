@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportDryRun bool
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export secrets to external store",
+	Long:  ``,
+	Args:  cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.PersistentFlags().BoolVarP(&exportDryRun, "dry-run", "d", false, "Show what would be exported without writing")
+}
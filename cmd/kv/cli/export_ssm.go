@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	awsSsm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+	"github.com/yousysadmin/kv/internal/importer/amazon"
+	"github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+)
+
+var (
+	exportSsmAws       amazon.Flags
+	exportSsmKMSKeyID  string
+	exportSsmOverwrite bool
+	exportSsmPrune     bool
+)
+
+// exportSsmCmd represents the export ssm command
+var exportSsmCmd = &cobra.Command{
+	Use:   "ssm <path>",
+	Short: "Export a bucket to AWS SSM Parameter Store",
+	Long: `Write every key of the bucket to AWS Systems Manager (SSM) Parameter Store as a SecureString parameter named <path>/<key>.
+Parameters that already have the same value are not written again. Existing parameters with another value
+are reported as conflicts and kept unless --overwrite is set. With --prune, parameters under the path that are
+not in the bucket are deleted. Expired keys are not exported.`,
+	Example: `
+  # Export the prod bucket under /prod/app/ using the default profile
+  kv export ssm --bucket=prod /prod/app/
+
+  # Encrypt parameters with a customer managed KMS key
+  kv export ssm --bucket=prod --kms-key-id=alias/kv /prod/app/
+
+  # Update changed parameters and delete parameters no longer in the bucket
+  kv export ssm --bucket=prod --overwrite --prune /prod/app/
+
+  # Show what would be changed without writing
+  kv export ssm --bucket=prod --overwrite --prune --dry-run /prod/app/
+`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+
+		if bucketName == "" {
+			return errors.New("export to ssm failed: bucket name is required")
+		}
+
+		secrets, err := bucketSecrets(bucketName)
+		if err != nil {
+			return fmt.Errorf("export to ssm failed: %w", err)
+		}
+
+		ctx := cmd.Context()
+		cfg, err := exportSsmAws.Config(ctx)
+		if err != nil {
+			return fmt.Errorf("export to ssm failed: %w", err)
+		}
+		ssmClient := awsSsm.NewFromConfig(cfg)
+		changes, err := ssm.PutSecrets(ctx, ssmClient, path, secrets, ssm.ExportOptions{
+			KMSKeyID:  exportSsmKMSKeyID,
+			Overwrite: exportSsmOverwrite,
+			Prune:     exportSsmPrune,
+			DryRun:    exportDryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("export to ssm failed: %w", err)
+		}
+
+		var written, conflicts int
+		for _, c := range changes {
+			switch c.Action {
+			case ssm.ActionCreate, ssm.ActionUpdate, ssm.ActionDelete:
+				written++
+			case ssm.ActionConflict:
+				conflicts++
+			}
+		}
+
+		res := map[string]any{"bucket": bucketName, "path": path, "dry_run": exportDryRun, "changes": changes, "changed": written}
+		err = printResult(res, func() {
+			for _, c := range changes {
+				if exportDryRun {
+					fmt.Printf("DryRun[%s]: %s %s\n", bucketName, c.Action, c.Name)
+				} else {
+					fmt.Printf("Exported[%s]: %s %s\n", bucketName, c.Action, c.Name)
+				}
+			}
+			fmt.Printf("Changed %d parameters.\n", written)
+		})
+		if err != nil {
+			return err
+		}
+		if conflicts > 0 {
+			return fmt.Errorf("%d parameters were not overwritten, use --overwrite: %w", conflicts, ssm.ErrConflict)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportSsmCmd)
+
+	exportSsmAws.Register(exportSsmCmd.PersistentFlags())
+	exportSsmCmd.PersistentFlags().StringVar(&exportSsmKMSKeyID, "kms-key-id", "", "KMS key ID, ARN or alias to encrypt parameters (AWS managed key if not set)")
+	exportSsmCmd.PersistentFlags().BoolVar(&exportSsmOverwrite, "overwrite", false, "Overwrite existing parameters with another value")
+	exportSsmCmd.PersistentFlags().BoolVar(&exportSsmPrune, "prune", false, "Delete parameters under the path that are not in the bucket")
+}
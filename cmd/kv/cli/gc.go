@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var gcDryRun bool
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc [<bucket>...]",
+	Short: "Remove expired keys.",
+	Long: `This command removes expired keys and their history from the specified buckets,
+or from all buckets if none is specified.
+
+This action is permanent and cannot be undone.`,
+	Example: `
+  kv gc
+  kv gc ci-tokens
+  kv gc --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := storage.NewEntityStorage(kvdb, "")
+
+		buckets := args
+		if len(buckets) == 0 {
+			bl, err := s.ListBuckets()
+			if err != nil {
+				return fmt.Errorf("gc: list buckets failed: %w", err)
+			}
+			buckets = bl
+		}
+
+		now := time.Now()
+		var total int
+		removed := make(map[string][]string)
+		for _, b := range buckets {
+			var keys []string
+			if gcDryRun {
+				v, err := s.List(b, storage.ListOptions{})
+				if err != nil {
+					return fmt.Errorf("gc: bucket `%s` failed: %w", b, err)
+				}
+				for _, e := range v {
+					if e.Expired(now) {
+						keys = append(keys, e.Key)
+					}
+				}
+			} else {
+				var err error
+				keys, err = s.PurgeExpired(b, now)
+				if err != nil {
+					return fmt.Errorf("gc: bucket `%s` failed: %w", b, err)
+				}
+			}
+
+			for _, k := range keys {
+				if jsonOutput() {
+					continue
+				}
+				if gcDryRun {
+					fmt.Printf("DryRun[%s]: %s\n", b, k)
+				} else {
+					fmt.Printf("Removed[%s]: %s\n", b, k)
+				}
+			}
+			if len(keys) > 0 {
+				removed[b] = keys
+			}
+			total += len(keys)
+		}
+
+		res := map[string]any{"dry_run": gcDryRun, "removed": removed, "count": total}
+		return printResult(res, func() {
+			if gcDryRun {
+				fmt.Printf("Found %d expired keys.\n", total)
+			} else {
+				fmt.Printf("Removed %d expired keys.\n", total)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVarP(&gcDryRun, "dry-run", "d", false, "Show what would be removed without writing")
+}
@@ -3,12 +3,18 @@ package cli
 import (
 	"fmt"
 	"os"
+	"time"
 
-	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/models"
 
 	"github.com/spf13/cobra"
 )
 
+// expiryWarning is how long before expiry kv get starts to warn about it.
+const expiryWarning = 7 * 24 * time.Hour
+
+var getVersion uint64
+
 // getCmd represents the get command
 var getCmd = &cobra.Command{
 	Use:   "get <key>|<key@bucket>",
@@ -18,27 +24,49 @@ If a bucket is not specified, the default bucket will be used.`,
 	Example: `
   kv get username
   kv get username@production
-  kv get --bucket=production username`,
-	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
+  kv get --bucket=production username
+  kv get --version=2 username@production`,
+	Args:        cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Annotations: map[string]string{annotationReadOnly: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		k, b := parseKey(args[0])
 
-		encKey, err := selectKey(encryptionKeys, b)
+		s, err := bucketStorage(b)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
+		}
+
+		if getVersion > 0 {
+			v, err := s.GetVersion(b, k, getVersion)
+			if err != nil {
+				return fmt.Errorf("get key: `%s` failed: %w", k, err)
+			}
+			return printResult(map[string]any{"bucket": b, "key": k, "version": getVersion, "value": v}, func() {
+				fmt.Printf("%s", v)
+			})
 		}
 
-		s := storage.NewEntityStorage(kvdb, encKey)
-		v, err := s.Get(b, k)
+		e, err := s.GetEntity(b, k)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "get key: `%s` failed: %s\n", k, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("get key: `%s` failed: %w", k, err)
 		}
-		fmt.Printf("%s", v)
+		if e.ExpiresWithin(expiryWarning, time.Now()) {
+			fmt.Fprintf(os.Stderr, "warning: key `%s` expires at %s\n", k, e.ExpiresAt.Local().Format(time.RFC3339))
+		}
+		return printResult(bucketEntity{Bucket: b, Entity: e}, func() {
+			fmt.Printf("%s", e.Value)
+		})
 	},
 }
 
+// bucketEntity is the JSON output of a key with its bucket name.
+type bucketEntity struct {
+	Bucket string `json:"bucket"`
+	models.Entity
+}
+
 func init() {
 	rootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().Uint64Var(&getVersion, "version", 0, "get a specific version of the value (see kv history)")
 }
@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/yousysadmin/kv/internal/agent"
 	"github.com/yousysadmin/kv/internal/enckeystore"
+	"github.com/yousysadmin/kv/internal/storage"
 	"github.com/yousysadmin/kv/pkg/encrypt"
 )
 
@@ -30,7 +35,16 @@ func loadAllKeys(storePath, encryptionKey string) (map[string]string, *enckeysto
 
 	// Encryption Key Store init
 	ks := enckeystore.NewEncryptionKeyStore(storePath)
-	if err := ks.Load(); err != nil {
+	err := ks.Load()
+	if errors.Is(err, enckeystore.ErrPassphraseRequired) {
+		var p []byte
+		if p, err = readPassphrase("Enter key store passphrase: "); err != nil {
+			return nil, nil, err
+		}
+		ks.SetPassphrase(p)
+		err = ks.Load()
+	}
+	if err != nil {
 		return nil, nil, fmt.Errorf("load key store: %w", err)
 	}
 
@@ -56,6 +70,55 @@ func loadAllKeys(storePath, encryptionKey string) (map[string]string, *enckeysto
 	return keys, ks, nil
 }
 
+// bucketSecrets returns the values of all not expired keys in a bucket.
+func bucketSecrets(bucket string) (map[string]string, error) {
+	s, err := bucketStorage(bucket)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.List(bucket, storage.ListOptions{Values: true, SkipExpired: true})
+	if err != nil {
+		return nil, err
+	}
+	secrets := make(map[string]string, len(items))
+	for _, e := range items {
+		secrets[e.Key] = e.Value
+	}
+	return secrets, nil
+}
+
+// bucketStorage returns the storage for a bucket, values are encrypted by the agent
+// when it is used, otherwise with the bucket key from the Encryption Keys Store.
+func bucketStorage(bucket string) (*storage.EntityStorage, error) {
+	if agentClient != nil {
+		return storage.NewEntityStorageWithCipher(kvdb, agentClient), nil
+	}
+	encKey, err := selectKey(encryptionKeys, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewEntityStorage(kvdb, encKey), nil
+}
+
+// dialAgent connects to the agent at KV_AGENT_SOCK if it holds the keys of the key store at storePath.
+// It returns nil when there is no such agent, the keys are loaded from the key store then.
+func dialAgent(storePath string) *agent.Client {
+	sock := os.Getenv("KV_AGENT_SOCK")
+	if sock == "" {
+		return nil
+	}
+	c, err := agent.Dial(sock)
+	if err != nil {
+		return nil
+	}
+	st, err := c.Status()
+	if abs, _ := filepath.Abs(storePath); err != nil || st.KeyStore != abs {
+		c.Close()
+		return nil
+	}
+	return c
+}
+
 // selectKey chooses a key for a bucket from the Encryption Keys Store.
 func selectKey(keysStore map[string]string, bucket string) (string, error) {
 	if k, ok := keysStore[bucket]; ok && k != "" {
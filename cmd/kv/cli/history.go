@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <key>|<key@bucket>",
+	Short: "Show stored versions of a key.",
+	Long: `This command lists all stored versions of the key with their creation time.
+Every write keeps the previous value, use "kv get --version" to read
+an earlier version and "kv rollback" to restore it.
+The latest 50 versions are kept, older ones are removed on the next write.
+Deleting a key removes its history as well.
+
+Versions written before history was introduced have no creation time.`,
+	Example: `
+  kv history token
+  kv history token@prod`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		k, b := parseKey(args[0])
+
+		s := storage.NewEntityStorage(kvdb, "")
+		versions, err := s.History(b, k)
+		if err != nil {
+			return fmt.Errorf("history key: `%s` failed: %w", k, err)
+		}
+		return printResult(versions, func() {
+			for _, v := range versions {
+				created := "-"
+				if !v.CreatedAt.IsZero() {
+					created = v.CreatedAt.Local().Format(time.RFC3339)
+				}
+				fmt.Printf("%d\t%s\n", v.Version, created)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
@@ -1,20 +1,192 @@
 package cli
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
 	"github.com/spf13/cobra"
+	"github.com/yousysadmin/kv/internal/config"
+	"github.com/yousysadmin/kv/internal/importer"
+	_ "github.com/yousysadmin/kv/internal/importer/amazon/secretsmanager"
+	_ "github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+	_ "github.com/yousysadmin/kv/internal/importer/file"
+	_ "github.com/yousysadmin/kv/internal/importer/vault"
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/storage"
 )
 
 var (
-	importDryRun     bool
-	importShowValues bool
+	importDryRun      bool
+	importShowValues  bool
+	importOnConflict  string
+	importTrimKeyName bool
+	importKeyPrefix   string
+	importRename      []string
 )
 
 // importCmd represents the import command
 var importCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import secrets from external store",
-	Long:  ``,
-	Args:  cobra.NoArgs,
+	Long: `The import command stores secrets from an external store in a bucket.
+
+Key names can be changed before they are stored: --trim-key-name keeps only the final
+path part, --rename replaces names matching a regular expression (pattern=replacement,
+can be repeated) and --key-prefix prepends a prefix, in that order.
+
+All keys are imported in a single transaction, either all keys are stored or none is.
+Keys with the same value are left unchanged. A key that already exists with another value
+is handled by --on-conflict:
+  overwrite  store the new value as the next version of the key (default)
+  skip       keep the existing value
+  fail       abort the import without storing anything
+  rename     store the new value under <key>.<n>
+An existing value that can't be decrypted with the bucket key aborts the import.`,
+	Example: `
+  kv import ssm --bucket=mybucket --recursive --trim-key-name /prod/env/
+  kv import file --bucket=myservice --rename '^DB_(.*)$=database_$1' --key-prefix app/ .env`,
+	Args: cobra.NoArgs,
+}
+
+// newImportCmd creates the import subcommand of a registered importer.
+func newImportCmd(name string) *cobra.Command {
+	imp, err := importer.New(name)
+	if err != nil {
+		panic(err)
+	}
+	info := imp.Info()
+
+	cmd := &cobra.Command{
+		Use:     strings.TrimSpace(name + " " + info.Use),
+		Short:   info.Short,
+		Long:    info.Long,
+		Example: info.Example,
+		Args:    cobra.MatchAll(cobra.RangeArgs(info.MinArgs, info.MaxArgs), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyImportSettings(cmd, profile.Import[name]); err != nil {
+				return fmt.Errorf("import from %s failed: %w", name, err)
+			}
+			if bucketName == "" {
+				return fmt.Errorf("import from %s failed: bucket name is required", name)
+			}
+
+			mapping, err := importKeyMapping()
+			if err != nil {
+				return fmt.Errorf("import from %s failed: %w", name, err)
+			}
+
+			entities, err := imp.Fetch(cmd.Context(), args)
+			if err != nil {
+				return fmt.Errorf("import from %s failed: %w", name, err)
+			}
+			if entities, err = mapping.Apply(entities); err != nil {
+				return fmt.Errorf("import from %s failed: %w", name, err)
+			}
+
+			return importEntities(bucketName, entities)
+		},
+	}
+	cmd.Flags().AddFlagSet(imp.Flags())
+	return cmd
+}
+
+// applyImportSettings sets flags of an import command from the profile importer settings,
+// flags set on the command line are kept.
+func applyImportSettings(cmd *cobra.Command, settings map[string]config.Values) error {
+	for _, flag := range slices.Sorted(maps.Keys(settings)) {
+		f := cmd.Flags().Lookup(flag)
+		if f == nil {
+			return fmt.Errorf("profile setting %q: unknown flag", flag)
+		}
+		if f.Changed {
+			continue
+		}
+		for _, v := range settings[flag] {
+			if err := cmd.Flags().Set(flag, v); err != nil {
+				return fmt.Errorf("profile setting %q: %w", flag, err)
+			}
+		}
+	}
+	return nil
+}
+
+// importKeyMapping returns the key name mapping set by flags.
+func importKeyMapping() (importer.KeyMapping, error) {
+	m := importer.KeyMapping{TrimPath: importTrimKeyName, Prefix: importKeyPrefix}
+	for _, s := range importRename {
+		r, err := importer.ParseRename(s)
+		if err != nil {
+			return m, err
+		}
+		m.Rename = append(m.Rename, r)
+	}
+	return m, nil
+}
+
+// importEntities stores imported entities in a bucket, or only prints them with --dry-run.
+func importEntities(bucket string, entities []models.Entity) error {
+	policy, err := storage.ParseConflictPolicy(importOnConflict)
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	s, err := bucketStorage(bucket)
+	if err != nil {
+		return err
+	}
+
+	items, err := s.AddBatch(bucket, entities, storage.BatchOptions{OnConflict: policy, DryRun: importDryRun})
+	if err != nil {
+		return fmt.Errorf("import failed, nothing imported: %w", err)
+	}
+
+	label := "Imported"
+	if importDryRun {
+		label = "DryRun"
+	}
+	counts := make(map[storage.BatchAction]int)
+	results := make([]importItem, 0, len(items))
+	for i, it := range items {
+		counts[it.Action]++
+		r := importItem{BatchItem: it}
+		if importShowValues {
+			r.Value = entities[i].Value
+		}
+		results = append(results, r)
+	}
+
+	res := map[string]any{
+		"bucket":    bucket,
+		"dry_run":   importDryRun,
+		"items":     results,
+		"created":   counts[storage.BatchCreated],
+		"updated":   counts[storage.BatchUpdated],
+		"skipped":   counts[storage.BatchSkipped],
+		"unchanged": counts[storage.BatchUnchanged],
+	}
+	return printResult(res, func() {
+		for _, r := range results {
+			name := r.Key
+			if r.StoredAs != r.Key {
+				name = r.Key + " as " + r.StoredAs
+			}
+			if importShowValues {
+				fmt.Printf("%s[%s]: %s => %s (%s)\n", label, bucket, name, r.Value, r.Action)
+			} else {
+				fmt.Printf("%s[%s]: %s (%s)\n", label, bucket, name, r.Action)
+			}
+		}
+		fmt.Printf("Created %d, updated %d, skipped %d, unchanged %d keys.\n",
+			counts[storage.BatchCreated], counts[storage.BatchUpdated], counts[storage.BatchSkipped], counts[storage.BatchUnchanged])
+	})
+}
+
+// importItem is the result of an imported key, with its value if --values is set.
+type importItem struct {
+	storage.BatchItem
+	Value string `json:"value,omitempty"`
 }
 
 func init() {
@@ -22,4 +194,12 @@ func init() {
 
 	importCmd.PersistentFlags().BoolVarP(&importDryRun, "dry-run", "d", false, "Show what would be imported without writing")
 	importCmd.PersistentFlags().BoolVarP(&importShowValues, "values", "v", false, "Show key values during import")
+	importCmd.PersistentFlags().StringVar(&importOnConflict, "on-conflict", string(storage.ConflictOverwrite), "What to do with existing keys with another value [skip, overwrite, fail, rename]")
+	importCmd.PersistentFlags().BoolVarP(&importTrimKeyName, "trim-key-name", "t", false, "Trim key name to only the final path part (e.g., /foo/bar → bar)")
+	importCmd.PersistentFlags().StringVar(&importKeyPrefix, "key-prefix", "", "Prepend a prefix to key names")
+	importCmd.PersistentFlags().StringArrayVar(&importRename, "rename", nil, "Rename keys matching a regular expression (pattern=replacement), can be repeated")
+
+	for _, name := range importer.Names() {
+		importCmd.AddCommand(newImportCmd(name))
+	}
 }
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage encryption keys",
+	Long: `The keys command manages encryption keys in the encryption key store.
+
+It requires the encryption key store and can't be used with --encryption-key.`,
+	Example: `
+  kv keys rotate prod
+  kv keys upgrade`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoAgent: "true"},
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+}
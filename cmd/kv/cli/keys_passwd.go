@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	passwdNewPassphraseFile string
+	passwdRemove            bool
+)
+
+// keysPasswdCmd represents the keys passwd command
+var keysPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Set or change the encryption key store passphrase.",
+	Long: `This command seals the encryption key store with a new passphrase.
+
+For a plaintext key store this is a one-time migration: the store is encrypted
+with the passphrase and can't be read without it afterwards. For a protected store
+the current passphrase is required (KV_PASSPHRASE, --passphrase-file or prompt).
+
+The new passphrase is asked interactively twice, or read from --new-passphrase-file.
+Use --remove to store the keys in plaintext again.`,
+	Example: `
+  kv keys passwd
+  kv keys passwd --new-passphrase-file ./new-passphrase.txt
+  KV_PASSPHRASE=old kv keys passwd
+  kv keys passwd --remove`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if encryptionKenStore == nil {
+			return errors.New("change passphrase: failed: encryption key store is not used (--encryption-key is set)")
+		}
+
+		if passwdRemove {
+			encryptionKenStore.SetPassphrase(nil)
+			if err := encryptionKenStore.Save(); err != nil {
+				return fmt.Errorf("remove passphrase: save key store failed: %w", err)
+			}
+			return printResult(map[string]bool{"passphrase": false}, func() {
+				fmt.Println("remove passphrase: successfully, the key store is stored in plaintext")
+			})
+		}
+
+		p, err := readNewPassphrase()
+		if err != nil {
+			return fmt.Errorf("change passphrase: failed: %w", err)
+		}
+		encryptionKenStore.SetPassphrase(p)
+		if err := encryptionKenStore.Save(); err != nil {
+			return fmt.Errorf("change passphrase: save key store failed: %w", err)
+		}
+		return printResult(map[string]bool{"passphrase": true}, func() {
+			fmt.Println("change passphrase: successfully")
+		})
+	},
+}
+
+// readNewPassphrase reads a new passphrase from --new-passphrase-file or asks it twice.
+func readNewPassphrase() ([]byte, error) {
+	if passwdNewPassphraseFile != "" {
+		return readPassphraseFile(passwdNewPassphraseFile)
+	}
+	p, err := promptPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	confirm, err := promptPassphrase("Repeat new passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, confirm) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+func init() {
+	keysCmd.AddCommand(keysPasswdCmd)
+
+	keysPasswdCmd.Flags().StringVar(&passwdNewPassphraseFile, "new-passphrase-file", "", "read the new passphrase from a file")
+	keysPasswdCmd.Flags().BoolVar(&passwdRemove, "remove", false, "remove the passphrase and store keys in plaintext")
+}
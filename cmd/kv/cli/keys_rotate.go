@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yousysadmin/kv/internal/enckeystore"
+	"github.com/yousysadmin/kv/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// keysRotateCmd represents the keys rotate command
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate <bucket>",
+	Short: "Rotate the encryption key of a bucket.",
+	Long: `This command generates a new encryption key for the bucket and re-encrypts
+every value in the bucket, including key history, in a single transaction.
+
+A bucket that uses the default encryption key gets its own key.
+Rotating the "default" bucket rotates the default key and re-encrypts
+every bucket that doesn't have its own key.
+
+The previous key is kept in the encryption key store as retired until
+re-encryption is committed. If rotation is interrupted, run it again to finish.`,
+	Example: `
+  kv keys rotate prod
+  kv keys rotate default`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bucket := args[0]
+
+		if encryptionKenStore == nil {
+			return errors.New("rotate key: failed: encryption key store is not used (--encryption-key is set)")
+		}
+
+		n, err := rotateBucketKey(bucket)
+		if err != nil {
+			return fmt.Errorf("rotate key: bucket %s failed: %w", bucket, err)
+		}
+
+		return printResult(map[string]any{"bucket": bucket, "reencrypted": n}, func() {
+			fmt.Printf("rotate key: bucket %s successfully, %d values re-encrypted\n", bucket, n)
+		})
+	},
+}
+
+// rotateBucketKey replaces the bucket key with a new one and re-encrypts the bucket values.
+// The "default" bucket rotates the default key for all buckets without their own key.
+// It returns the number of re-encrypted values.
+func rotateBucketKey(bucket string) (int, error) {
+	buckets := []string{bucket}
+	if bucket == storage.DefaultBucket {
+		bl, err := storage.NewEntityStorage(kvdb, "").ListBuckets()
+		if err != nil {
+			return 0, err
+		}
+		buckets = buckets[:0]
+		for _, b := range bl {
+			if b == storage.DefaultBucket || !encryptionKenStore.HasKey(b) {
+				buckets = append(buckets, b)
+			}
+		}
+	}
+
+	oldKey, err := selectKey(encryptionKeys, bucket)
+	if err != nil {
+		return 0, err
+	}
+	// keys retired by an interrupted rotation can still be in use
+	oldKeys := []string{oldKey}
+	for _, k := range encryptionKenStore.RetiredKeys(bucket) {
+		oldKeys = append(oldKeys, string(k))
+	}
+
+	newKey, err := enckeystore.GenerateEncryptionKey()
+	if err != nil {
+		return 0, err
+	}
+	prev, err := encryptionKenStore.RotateKey(bucket, newKey)
+	if err != nil {
+		return 0, err
+	}
+	if err := encryptionKenStore.Save(); err != nil {
+		return 0, fmt.Errorf("save key store: %w", err)
+	}
+
+	s := storage.NewEntityStorage(kvdb, string(newKey))
+	n, err := s.Reencrypt(buckets, oldKeys)
+	if err != nil {
+		encryptionKenStore.CancelRotation(bucket, prev)
+		if serr := encryptionKenStore.Save(); serr != nil {
+			return 0, fmt.Errorf("re-encrypt: %w, restore key store: %s", err, serr.Error())
+		}
+		return 0, fmt.Errorf("re-encrypt: %w", err)
+	}
+
+	encryptionKenStore.FinishRotation(bucket)
+	if err := encryptionKenStore.Save(); err != nil {
+		return 0, fmt.Errorf("save key store: %w", err)
+	}
+
+	// keep the loaded keys in sync for following rotations
+	encryptionKeys[bucket] = string(newKey)
+	return n, nil
+}
+
+func init() {
+	keysCmd.AddCommand(keysRotateCmd)
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+
+	"github.com/spf13/cobra"
+)
+
+// keysUpgradeCmd represents the keys upgrade command
+var keysUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Replace legacy encryption keys with full-strength keys.",
+	Long: `This command rotates every legacy encryption key in the encryption key store.
+
+Keys generated by older versions of kv are printable strings and carry about 192 bits
+of entropy for AES-256. New keys are full-strength random bytes stored with an encoding
+prefix ("b64:"). Every bucket that uses a legacy key is re-encrypted, see "kv keys rotate".`,
+	Example: `
+  kv keys upgrade`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if encryptionKenStore == nil {
+			return errors.New("upgrade keys: failed: encryption key store is not used (--encryption-key is set)")
+		}
+
+		// bucket keys first, the default key re-encrypts all buckets without own key
+		var buckets []string
+		for _, b := range encryptionKenStore.ListBuckets() {
+			if b != storage.DefaultBucket && encrypt.IsLegacyAESKey(encryptionKeys[b]) {
+				buckets = append(buckets, b)
+			}
+		}
+		if encrypt.IsLegacyAESKey(encryptionKeys[storage.DefaultBucket]) {
+			buckets = append(buckets, storage.DefaultBucket)
+		}
+
+		reencrypted := make(map[string]int, len(buckets))
+		for _, b := range buckets {
+			n, err := rotateBucketKey(b)
+			if err != nil {
+				return fmt.Errorf("upgrade key: bucket %s failed: %w", b, err)
+			}
+			if !jsonOutput() {
+				fmt.Printf("upgrade key: bucket %s successfully, %d values re-encrypted\n", b, n)
+			}
+			reencrypted[b] = n
+		}
+		return printResult(map[string]any{"reencrypted": reencrypted, "count": len(buckets)}, func() {
+			fmt.Printf("Upgraded %d keys.\n", len(buckets))
+		})
+	},
+}
+
+func init() {
+	keysCmd.AddCommand(keysUpgradeCmd)
+}
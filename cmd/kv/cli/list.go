@@ -13,7 +13,8 @@ var listCmd = &cobra.Command{
   kv list keys
   kv list keys mybucket
   kv list buckets`,
-	Args: cobra.NoArgs,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationReadOnly: "true"},
 	//Run: func(cmd *cobra.Command, args []string) {},
 }
 
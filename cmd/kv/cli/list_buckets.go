@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/yousysadmin/kv/internal/storage"
 
@@ -16,16 +15,20 @@ var listBucketsCmd = &cobra.Command{
 	Long: `List all available buckets in the encrypted key-value database.
 
 This command retrieves and prints the names of all top-level buckets stored in the database.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		s := storage.NewEntityStorage(kvdb, "")
 		bl, err := s.ListBuckets()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "list bucketets: failed: %s\n", err.Error())
-			os.Exit(1)
+			return fmt.Errorf("list bucketets: failed: %w", err)
 		}
-		for _, b := range bl {
-			fmt.Println(b)
+		if bl == nil {
+			bl = []string{}
 		}
+		return printResult(bl, func() {
+			for _, b := range bl {
+				fmt.Println(b)
+			}
+		})
 	},
 }
 
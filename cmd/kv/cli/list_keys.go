@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/yousysadmin/kv/internal/importer"
 	"github.com/yousysadmin/kv/internal/models"
 	"github.com/yousysadmin/kv/internal/storage"
 	"github.com/yousysadmin/kv/internal/utils"
@@ -17,6 +19,10 @@ import (
 var (
 	withValues bool
 	format     string
+	filterTags []string
+	expiringIn string
+	renameKeys []string
+	k8sSecret  utils.K8sSecretOptions
 )
 
 // listKeysCmd represents the keys command
@@ -24,11 +30,31 @@ var listKeysCmd = &cobra.Command{
 	Use:   "keys [<bucket>]",
 	Short: "List keys.",
 	Long: `This command outputs all key names in the current or specified bucket.
-It does not display values, only the stored keys.`,
+It does not display values, only the stored keys.
+
+The json format also includes key metadata: timestamps, writer, description, tags and expiry time.
+With --expiring-within, only keys that expire within the given period (or already expired)
+are listed, the raw format then includes the expiry time.
+
+The shell, fish, powershell and systemd (EnvironmentFile) formats set environment variables
+with key names normalized like in the dotenv format, e.g. in fish: kv list keys -f fish -v | source
+A key that doesn't become a valid variable name (e.g. 1TOKEN), or two keys that become
+the same name (e.g. a-b and a_b), fail the command instead of being dropped.
+
+The k8s-secret format outputs an Opaque v1/Secret manifest named by --name with base64 encoded
+values in data, or plain values in stringData with --string-data. It requires --values.
+Secret keys may contain only alphanumeric characters, '-', '_' and '.', other key names can be
+changed with --rename pattern=replacement (can be repeated).`,
 	Example: `
   kv list keys
   kv list keys mybucket
-  kv list keys --bucket=mybucket`,
+  kv list keys --bucket=mybucket
+  kv list keys --format=json
+  eval "$(kv list keys --format=shell --values)"
+  kv list keys --tag team=payments --tag env
+  kv list keys --expiring-within 7d
+  kv list keys --format=k8s-secret --values --name app-secrets --namespace prod
+  kv list keys --format=k8s-secret --values --name app-secrets --string-data --rename '/=.'`,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		s := storage.NewEntityStorage(kvdb, "")
 		bl, err := s.ListBuckets()
@@ -44,7 +70,7 @@ It does not display values, only the stored keys.`,
 		}
 		return out, cobra.ShellCompDirectiveNoFileComp
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		var bucket string
 		if len(args) == 0 {
 			bucket = bucketName
@@ -52,29 +78,70 @@ It does not display values, only the stored keys.`,
 			bucket = args[0]
 		}
 
-		encKey, err := selectKey(encryptionKeys, bucket)
+		// --output json prints keys in the json format unless another format is set
+		if jsonOutput() && !cmd.Flags().Changed("format") {
+			format = "json"
+		}
+
+		s, err := bucketStorage(bucket)
+		if err != nil {
+			return err
+		}
+
+		tags, err := utils.ParseTags(filterTags)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return fmt.Errorf("list keys in bucket: `%s` failed: %w", bucket, err)
 		}
 
-		s := storage.NewEntityStorage(kvdb, encKey)
-		v, err := s.List(bucket, withValues)
+		v, err := s.List(bucket, storage.ListOptions{Values: withValues, SkipExpired: withValues})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "list keys in bucket: `%s` failed: %s\n", bucket, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("list keys in bucket: `%s` failed: %w", bucket, err)
+		}
+		if tags != nil {
+			v = slices.DeleteFunc(v, func(e models.Entity) bool { return !e.MatchTags(tags) })
+		}
+		if expiringIn != "" {
+			d, err := utils.ParseDuration(expiringIn)
+			if err != nil {
+				return fmt.Errorf("list keys in bucket: `%s` failed: %w", bucket, err)
+			}
+			now := time.Now()
+			v = slices.DeleteFunc(v, func(e models.Entity) bool { return !e.ExpiresWithin(d, now) })
+			if format == "raw" && !withValues {
+				printExpiry(v)
+				return nil
+			}
+		}
+		if len(renameKeys) > 0 {
+			var m importer.KeyMapping
+			for _, r := range renameKeys {
+				rule, err := importer.ParseRename(r)
+				if err != nil {
+					return fmt.Errorf("list keys in bucket: `%s` failed: %w", bucket, err)
+				}
+				m.Rename = append(m.Rename, rule)
+			}
+			if v, err = m.Apply(v); err != nil {
+				return fmt.Errorf("list keys in bucket: `%s` failed: %w", bucket, err)
+			}
 		}
 		if err := outputKeyList(v); err != nil {
-			fmt.Fprintf(os.Stderr, "list keys in bucket: `%s` failed: %s\n", bucket, err.Error())
-			os.Exit(1)
+			return fmt.Errorf("list keys in bucket: `%s` failed: %w", bucket, err)
 		}
+		return nil
 	},
 }
 
 func init() {
 	listCmd.AddCommand(listKeysCmd)
-	listKeysCmd.PersistentFlags().BoolVarP(&withValues, "values", "v", false, "decrypt and output values")
-	listKeysCmd.PersistentFlags().StringVarP(&format, "format", "f", "raw", "output format [raw, json, dotenv, rails-dotenv]")
+	listKeysCmd.PersistentFlags().BoolVarP(&withValues, "values", "v", false, "decrypt and output values, expired keys are left out")
+	listKeysCmd.PersistentFlags().StringVarP(&format, "format", "f", "raw", "output format [raw, json, yaml, toml, dotenv, rails-dotenv, shell, fish, powershell, systemd, k8s-secret]")
+	listKeysCmd.PersistentFlags().StringArrayVar(&filterTags, "tag", nil, "show only keys with the tag (key=value or key), can be repeated")
+	listKeysCmd.PersistentFlags().StringVar(&expiringIn, "expiring-within", "", "show only keys that expire within the period, e.g. 7d, 72h")
+	listKeysCmd.PersistentFlags().StringArrayVar(&renameKeys, "rename", nil, "rename keys in the output matching a regular expression (pattern=replacement), can be repeated")
+	listKeysCmd.PersistentFlags().StringVar(&k8sSecret.Name, "name", "", "secret name for the k8s-secret format")
+	listKeysCmd.PersistentFlags().StringVar(&k8sSecret.Namespace, "namespace", "", "secret namespace for the k8s-secret format")
+	listKeysCmd.PersistentFlags().BoolVar(&k8sSecret.StringData, "string-data", false, "put plain values in stringData for the k8s-secret format")
 }
 
 // outputKeyList print list of keys in plaintext or json format
@@ -92,6 +159,33 @@ func outputKeyList(data []models.Entity) error {
 		if err := printJson(data); err != nil {
 			return err
 		}
+	case "yaml":
+		o, err := utils.ToYAML(data, withValues)
+		if err != nil {
+			return err
+		}
+		fmt.Print(o)
+	case "toml":
+		o, err := utils.ToTOML(data, withValues)
+		if err != nil {
+			return err
+		}
+		fmt.Print(o)
+	case "shell", "fish", "powershell", "systemd":
+		o, err := utils.ToShell(data, withValues, utils.ShellMode(format))
+		if err != nil {
+			return err
+		}
+		fmt.Print(o)
+	case "k8s-secret":
+		if !withValues {
+			return errors.New("the k8s-secret format requires --values")
+		}
+		o, err := utils.ToK8sSecret(data, k8sSecret)
+		if err != nil {
+			return err
+		}
+		fmt.Print(o)
 	default:
 		return errors.New("unkown output format")
 
@@ -120,6 +214,9 @@ func printDotenv(data []models.Entity, format string, withValues bool) error {
 
 // printJson prinv list of kv as json
 func printJson(data []models.Entity) error {
+	if data == nil {
+		data = []models.Entity{}
+	}
 	if data, err := json.Marshal(data); err == nil {
 		fmt.Println(string(data))
 	} else {
@@ -128,6 +225,13 @@ func printJson(data []models.Entity) error {
 	return nil
 }
 
+// printExpiry print keys with expiry time
+func printExpiry(data []models.Entity) {
+	for _, kv := range data {
+		fmt.Printf("%s\t%s\n", kv.Key, kv.ExpiresAt.Local().Format(time.RFC3339))
+	}
+}
+
 // printRaw print kv as raw
 func printRaw(data []models.Entity, withValues bool) error {
 	for _, kv := range data {
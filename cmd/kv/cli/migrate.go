@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/yousysadmin/kv/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [<bucket>...]",
+	Short: "Upgrade stored values to the current format.",
+	Long: `This command upgrades values in the specified buckets, or in all buckets if none is specified,
+to the current ciphertext format.
+
+Values written by older versions of kv are not bound to their bucket and key name,
+so they can be swapped in the database file without being detected. Migrated values
+are authenticated with their bucket and key name and are rejected if moved.
+
+When all buckets are migrated, the database is marked as migrated and values in the old
+format are rejected from then on, so an old value can't be put back into the file.`,
+	Example: `
+  kv migrate
+  kv migrate prod stage`,
+	Annotations: map[string]string{annotationNoAgent: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		buckets := args
+		if len(buckets) == 0 {
+			bl, err := storage.NewEntityStorage(kvdb, "").ListBuckets()
+			if err != nil {
+				return fmt.Errorf("migrate: list buckets failed: %w", err)
+			}
+			buckets = bl
+		}
+
+		var total int
+		migrated := make(map[string]int, len(buckets))
+		for _, b := range buckets {
+			encKey, err := selectKey(encryptionKeys, b)
+			if err != nil {
+				return fmt.Errorf("migrate: bucket `%s` failed: %w", b, err)
+			}
+
+			n, err := storage.NewEntityStorage(kvdb, encKey).Migrate([]string{b})
+			if err != nil {
+				return fmt.Errorf("migrate: bucket `%s` failed: %w", b, err)
+			}
+			if !jsonOutput() {
+				fmt.Printf("Migrated[%s]: %d values\n", b, n)
+			}
+			migrated[b] = n
+			total += n
+		}
+		// values in the old format may remain in buckets that were not migrated
+		marked := len(args) == 0
+		if marked {
+			if err := storage.NewEntityStorage(kvdb, "").MarkMigrated(); err != nil {
+				return fmt.Errorf("migrate: mark the database as migrated failed: %w", err)
+			}
+		}
+		return printResult(map[string]any{"migrated": migrated, "count": total, "marked": marked}, func() {
+			fmt.Printf("Migrated %d values.\n", total)
+			if marked {
+				fmt.Println("Values in the old format are rejected from now on.")
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yousysadmin/kv/internal/backup"
+	"github.com/yousysadmin/kv/internal/doctor"
+	"github.com/yousysadmin/kv/internal/enckeystore"
+	"github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/utils"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Exit codes returned by kv, scripts can rely on them.
+const (
+	exitError          = 1  // any other error
+	exitUsage          = 2  // invalid command, arguments or flags
+	exitNotFound       = 3  // key or version not found
+	exitExpired        = 4  // key is expired
+	exitBucketNotFound = 5  // bucket not found
+	exitDecryptFailed  = 6  // value or key store can't be decrypted
+	exitInvalidKey     = 7  // invalid key name or encryption key
+	exitLockTimeout    = 8  // database is locked by another process
+	exitConflict       = 9  // key or parameter exists with another value
+	exitCheckFailed    = 10 // integrity check found problems
+)
+
+var (
+	outputFormat string
+
+	// commandStarted is set when flags and arguments are validated and the command starts,
+	// an error before that is a usage error.
+	commandStarted bool
+)
+
+// errorCode describes an error class for scripts.
+type errorCode struct {
+	Name     string
+	ExitCode int
+}
+
+// classifyError returns the error class of an error returned by a command.
+func classifyError(err error) errorCode {
+	switch {
+	case !commandStarted:
+		return errorCode{"usage", exitUsage}
+	case errors.Is(err, storage.ErrValueIsEmpty),
+		errors.Is(err, storage.ErrVersionNotFound):
+		return errorCode{"not_found", exitNotFound}
+	case errors.Is(err, storage.ErrExpired):
+		return errorCode{"expired", exitExpired}
+	case errors.Is(err, storage.ErrBucketNotFound):
+		return errorCode{"bucket_not_found", exitBucketNotFound}
+	case errors.Is(err, storage.ErrIntegrity),
+		errors.Is(err, storage.ErrLegacyValue),
+		errors.Is(err, storage.ErrNoDecryptionKey),
+		errors.Is(err, encrypt.ErrorDecryptionFailed),
+		errors.Is(err, encrypt.ErrorChipperTooShort),
+		errors.Is(err, encrypt.ErrorBase64Decode),
+		errors.Is(err, enckeystore.ErrWrongPassphrase),
+		errors.Is(err, backup.ErrWrongPassphrase):
+		return errorCode{"decrypt_failed", exitDecryptFailed}
+	case errors.Is(err, storage.ErrInvalidKeyName),
+		errors.Is(err, utils.ErrInvalidVariableName),
+		errors.Is(err, utils.ErrVariableCollision),
+		errors.Is(err, encrypt.ErrorInvalidKey),
+		errors.Is(err, encrypt.ErrorInvalidKeyLength),
+		errors.Is(err, ssm.ErrInvalidKeyName):
+		return errorCode{"invalid_key", exitInvalidKey}
+	case errors.Is(err, storage.ErrLockTimeout):
+		return errorCode{"lock_timeout", exitLockTimeout}
+	case errors.Is(err, storage.ErrConflict),
+		errors.Is(err, ssm.ErrConflict),
+		errors.Is(err, backup.ErrExists):
+		return errorCode{"conflict", exitConflict}
+	case errors.Is(err, doctor.ErrFailed):
+		return errorCode{"check_failed", exitCheckFailed}
+	}
+	return errorCode{"error", exitError}
+}
+
+// jsonOutput reports whether results are printed as JSON (--output json).
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// printResult prints v as JSON with --output json, otherwise text prints the human readable result.
+func printResult(v any, text func()) error {
+	if jsonOutput() {
+		return writeJSON(os.Stdout, v)
+	}
+	text()
+	return nil
+}
+
+// printError prints an error of the command to STDERR, as a JSON object with --output json,
+// and returns the exit code for it.
+func printError(cmd *cobra.Command, err error) int {
+	code := classifyError(err)
+	if jsonOutput() {
+		_ = writeJSON(os.Stderr, map[string]any{
+			"error": map[string]any{
+				"code":      code.Name,
+				"exit_code": code.ExitCode,
+				"message":   err.Error(),
+			},
+		})
+		return code.ExitCode
+	}
+	fmt.Fprintln(os.Stderr, err.Error())
+	if code.ExitCode == exitUsage {
+		fmt.Fprintf(os.Stderr, "Run \"%s --help\" for usage.\n", cmd.CommandPath())
+	}
+	return code.ExitCode
+}
+
+// writeJSON writes v as a JSON line.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
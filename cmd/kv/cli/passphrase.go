@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// readPassphrase returns the key store passphrase from the KV_PASSPHRASE environment variable,
+// the --passphrase-file file or an interactive prompt, in that order.
+func readPassphrase(prompt string) ([]byte, error) {
+	if p := os.Getenv("KV_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	if path := viper.GetString("passphrase-file"); path != "" {
+		return readPassphraseFile(path)
+	}
+	return promptPassphrase(prompt)
+}
+
+// readPassphraseFile reads a passphrase from the first line of a file.
+func readPassphraseFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase file: %w", err)
+	}
+	p, _, _ := bytes.Cut(data, []byte("\n"))
+	p = bytes.TrimSuffix(p, []byte("\r"))
+	if len(p) == 0 {
+		return nil, fmt.Errorf("passphrase file %s is empty", path)
+	}
+	return p, nil
+}
+
+// promptPassphrase reads a passphrase from the terminal without echo.
+func promptPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("encryption key store is protected by a passphrase: set KV_PASSPHRASE or use --passphrase-file")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	if len(p) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	return p, nil
+}
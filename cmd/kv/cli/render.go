@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yousysadmin/kv/internal/render"
+	"github.com/yousysadmin/kv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderInput  string
+	renderOutput string
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render a template with values from buckets.",
+	Long: `This command evaluates a Go text/template file with functions that read keys from kv.
+
+Template functions:
+  kv "key@bucket"         value of a key, "key" reads from the default bucket
+  bucket "prod"           map of all key values in a bucket (expired keys are skipped)
+  dotenv (bucket "prod")  dotenv lines for a map of values
+  b64enc "value"          base64 encoded value
+  default "x" .Value      .Value or "x" when .Value is empty
+
+A missing or expired key fails rendering. For optional keys, use a bucket map:
+  {{ index (bucket "prod") "port" | default "8080" }}
+
+The output file is written atomically with 0600 permissions.
+Without --output-file the result is printed to STDOUT.`,
+	Example: `
+  kv render -i config.tmpl -o config.yaml
+  kv render -i config.tmpl --bucket prod > config.yaml
+  # read template from STDIN
+  echo 'password: {{ kv "db-pass@prod" }}' | kv render -i -`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationReadOnly: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := readTemplate(renderInput)
+		if err != nil {
+			return fmt.Errorf("render: failed: %w", err)
+		}
+
+		out, err := render.Render(renderInput, tmpl, storeSource{})
+		if err != nil {
+			return fmt.Errorf("render: %s failed: %w", renderInput, err)
+		}
+
+		if renderOutput == "" || renderOutput == "-" {
+			return printResult(map[string]string{"content": string(out)}, func() {
+				os.Stdout.Write(out)
+			})
+		}
+		if err := utils.AtomicWriteFile(renderOutput, out, 0o600); err != nil {
+			return fmt.Errorf("render: write %s failed: %w", renderOutput, err)
+		}
+		return printResult(map[string]any{"output": renderOutput, "bytes": len(out)}, func() {})
+	},
+}
+
+// readTemplate reads a template from a file or from STDIN for "-".
+func readTemplate(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("template is not set, use --input")
+	}
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// storeSource resolves template keys from the database.
+type storeSource struct{}
+
+// Get returns the value of a key in "key" or "key@bucket" form.
+func (storeSource) Get(ref string) (string, error) {
+	k, b := parseKey(ref)
+	s, err := bucketStorage(b)
+	if err != nil {
+		return "", err
+	}
+	v, err := s.Get(b, k)
+	if err != nil {
+		return "", fmt.Errorf("key %s: %w", ref, err)
+	}
+	return v, nil
+}
+
+// Bucket returns all not expired key values in a bucket.
+func (storeSource) Bucket(bucket string) (map[string]string, error) {
+	values, err := bucketSecrets(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("bucket %s: %w", bucket, err)
+	}
+	return values, nil
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVarP(&renderInput, "input", "i", "", "template file, - for STDIN")
+	renderCmd.Flags().StringVarP(&renderOutput, "output-file", "o", "", "output file, STDOUT if not set")
+}
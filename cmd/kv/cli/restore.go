@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.etcd.io/bbolt"
+
+	"github.com/yousysadmin/kv/internal/backup"
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+var (
+	restoreInput  string
+	restoreForce  bool
+	restoreKeys   bool
+	restoreDryRun bool
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore [<bucket>...]",
+	Short: "Restore the database from a backup archive.",
+	Long: `This command restores the database, or only the specified buckets, from an archive
+written by "kv backup". The archive manifest and checksums are verified first,
+nothing is restored from an archive that doesn't match them.
+
+Without buckets the content of the whole database is replaced in a single transaction,
+the file is changed in place, so other kv processes waiting for the database lock see
+the restored data. An existing database is not replaced without --force. With buckets only those buckets (with key history) are copied
+to the database in a single transaction, existing buckets are replaced only with --force.
+
+With --keys the encryption key store is restored from the archive too, the backup
+passphrase is read from KV_BACKUP_PASSPHRASE, --backup-passphrase-file or asked interactively.
+An existing key store is not replaced without --force.
+
+Use --dry-run to only verify the archive and show its manifest.`,
+	Example: `
+  kv restore -i kv-2026-10-17.kvbak
+  kv restore -i kv-2026-10-17.kvbak --force --keys
+  kv restore -i kv-2026-10-17.kvbak --force prod
+  kv restore -i kv-2026-10-17.kvbak --dry-run`,
+	Args:        cobra.ArbitraryArgs,
+	Annotations: map[string]string{annotationNoKeys: "true", annotationNoDatabase: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath := viper.GetString("db")
+		storePath := viper.GetString("encryption-key-store")
+
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0o700); err != nil {
+			return fmt.Errorf("restore: failed: %w", err)
+		}
+		// extract next to the database, the snapshot is as large as the database
+		dir, err := os.MkdirTemp(filepath.Dir(dbPath), ".kv-restore-*")
+		if err != nil {
+			return fmt.Errorf("restore: failed: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		a, err := openArchive(restoreInput, dir)
+		if err != nil {
+			return fmt.Errorf("restore: %s failed: %w", restoreInput, err)
+		}
+		snap, err := bbolt.Open(a.DBPath, 0o600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+		if err != nil {
+			return fmt.Errorf("restore: %s failed: open database snapshot: %w", restoreInput, err)
+		}
+		defer snap.Close()
+
+		if restoreDryRun {
+			return printResult(map[string]any{"input": restoreInput, "manifest": a.Manifest}, func() {
+				printManifest(a.Manifest)
+			})
+		}
+
+		// check everything before changing anything
+		var keyStore []byte
+		if restoreKeys {
+			if !a.Manifest.HasKeyStore() {
+				return fmt.Errorf("restore: failed: %w", backup.ErrNoKeyStore)
+			}
+			if err := checkReplace(storePath, "key store"); err != nil {
+				return fmt.Errorf("restore: failed: %w", err)
+			}
+			p, err := readBackupPassphrase(false)
+			if err != nil {
+				return fmt.Errorf("restore: failed: %w", err)
+			}
+			if keyStore, err = a.KeyStore(p); err != nil {
+				return fmt.Errorf("restore: key store failed: %w", err)
+			}
+		}
+
+		// the key store is written next to its path before the database is changed
+		// and renamed into place after, so a failed write changes neither of them
+		var keyStoreTmp string
+		if keyStore != nil {
+			if err := os.MkdirAll(filepath.Dir(storePath), 0o700); err != nil {
+				return fmt.Errorf("restore: write key store failed: %w", err)
+			}
+			keyStoreTmp = storePath + ".restore"
+			defer os.Remove(keyStoreTmp)
+			if err := utils.AtomicWriteFile(keyStoreTmp, keyStore, 0o600); err != nil {
+				return fmt.Errorf("restore: write key store failed: %w", err)
+			}
+		}
+
+		if len(args) > 0 {
+			db, err := storage.Open(dbPath, viper.GetDuration("lock-timeout"), false)
+			if err != nil {
+				return fmt.Errorf("restore: failed: %w", err)
+			}
+			defer db.Close()
+			if err := backup.RestoreBuckets(db, snap, args, restoreForce); err != nil {
+				if errors.Is(err, backup.ErrExists) {
+					err = fmt.Errorf("%w, use --force to replace it", err)
+				}
+				return fmt.Errorf("restore: failed: %w", err)
+			}
+		} else {
+			if err := checkReplace(dbPath, "database"); err != nil {
+				return fmt.Errorf("restore: failed: %w", err)
+			}
+			if err := replaceDatabase(dbPath, snap); err != nil {
+				return fmt.Errorf("restore: failed: %w", err)
+			}
+		}
+
+		if keyStore != nil {
+			if err := os.Rename(keyStoreTmp, storePath); err != nil {
+				return fmt.Errorf("restore: write key store failed: %w", err)
+			}
+		}
+
+		buckets := args
+		if len(buckets) == 0 {
+			buckets = a.Manifest.Buckets
+		}
+		res := map[string]any{"input": restoreInput, "buckets": buckets, "key_store": keyStore != nil}
+		return printResult(res, func() {
+			fmt.Printf("restore: %d buckets from %s (created at %s) successfully\n",
+				len(buckets), restoreInput, a.Manifest.CreatedAt.Format(time.RFC3339))
+			if keyStore != nil {
+				fmt.Printf("restore: key store %s successfully\n", storePath)
+			}
+		})
+	},
+}
+
+// openArchive extracts and verifies an archive file, or STDIN for "-", to dir.
+func openArchive(path, dir string) (*backup.Archive, error) {
+	if path == "" {
+		return nil, errors.New("archive is not set, use --input")
+	}
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return backup.Open(r, dir)
+}
+
+// checkReplace returns backup.ErrExists if the file exists and --force is not set.
+func checkReplace(path, what string) error {
+	if _, err := os.Stat(path); err == nil && !restoreForce {
+		return fmt.Errorf("%s %s: %w, use --force to replace it", what, path, backup.ErrExists)
+	}
+	return nil
+}
+
+// replaceDatabase replaces the content of the database with the snapshot in a single
+// transaction. The database file is changed in place rather than renamed over: a kv process
+// waiting for the lock of the old file would otherwise write to the unlinked file after
+// the rename, and its changes would be lost. A missing database is created.
+func replaceDatabase(dbPath string, snap *bbolt.DB) error {
+	db, err := storage.Open(dbPath, viper.GetDuration("lock-timeout"), false)
+	if err != nil {
+		return err
+	}
+	if err := backup.RestoreAll(db, snap); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// printManifest prints the archive manifest.
+func printManifest(m backup.Manifest) {
+	fmt.Printf("Format version: %d\n", m.FormatVersion)
+	fmt.Printf("Created at:     %s\n", m.CreatedAt.Format(time.RFC3339))
+	if m.KVVersion != "" {
+		fmt.Printf("kv version:     %s\n", m.KVVersion)
+	}
+	fmt.Printf("Buckets:        %d\n", len(m.Buckets))
+	for _, b := range m.Buckets {
+		fmt.Printf("  %s\n", b)
+	}
+	fmt.Println("Files:")
+	for _, f := range m.Files {
+		fmt.Printf("  %s\t%d bytes\tsha256:%s\n", f.Name, f.Size, f.SHA256)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().StringVarP(&restoreInput, "input", "i", "", "archive file, - for STDIN")
+	restoreCmd.Flags().BoolVarP(&restoreForce, "force", "f", false, "replace an existing database, buckets or key store")
+	restoreCmd.Flags().BoolVar(&restoreKeys, "keys", false, "restore the encryption key store from the archive")
+	restoreCmd.Flags().BoolVarP(&restoreDryRun, "dry-run", "d", false, "only verify the archive and show its manifest")
+	restoreCmd.Flags().StringVar(&backupPassphraseFile, "backup-passphrase-file", "", "read the backup passphrase from a file (can also use KV_BACKUP_PASSPHRASE)")
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <key>|<key@bucket> <version>",
+	Short: "Restore an earlier version of a key.",
+	Long: `This command makes the specified version of the key current again.
+The restored value is saved as a new version, so a rollback can be undone as well.
+The expiry of the version is restored with it, a version stored without expiry
+keeps the current expiry of the key.
+
+Use "kv history" to list available versions.`,
+	Example: `
+  kv rollback token 3
+  kv rollback token@prod 3`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		k, b := parseKey(args[0])
+
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil || version == 0 {
+			return fmt.Errorf("rollback key: %s failed: invalid version %q", k, args[1])
+		}
+
+		s, err := bucketStorage(b)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Rollback(b, k, version); err != nil {
+			return fmt.Errorf("rollback key: %s failed: %w", k, err)
+		}
+
+		return printResult(map[string]any{"bucket": b, "key": k, "version": version}, func() {
+			fmt.Printf("rollback key: %s to version %d successfully\n", k, version)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -5,9 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/yousysadmin/kv/internal/agent"
+	"github.com/yousysadmin/kv/internal/config"
 	"github.com/yousysadmin/kv/internal/enckeystore"
 	"github.com/yousysadmin/kv/internal/storage"
 	"go.etcd.io/bbolt"
@@ -18,6 +21,26 @@ var (
 	encryptionKenStore *enckeystore.EncryptionKeyStore
 	kvdb               *bbolt.DB
 	bucketName         string
+
+	// agentClient encrypts and decrypts values when the agent is used, encryption keys are not loaded then.
+	agentClient *agent.Client
+
+	// profile is the selected configuration profile, configErr is the error of loading it.
+	profile   config.Profile
+	configErr error
+)
+
+// Command annotations, set on a command they apply to its subcommands too.
+const (
+	// annotationReadOnly marks commands that only read the database.
+	annotationReadOnly = "kv/read-only"
+	// annotationNoAgent marks commands that need the encryption key store itself and load it
+	// even when the agent is running.
+	annotationNoAgent = "kv/no-agent"
+	// annotationNoKeys marks commands that don't use encryption keys.
+	annotationNoKeys = "kv/no-keys"
+	// annotationNoDatabase marks commands that don't use the database.
+	annotationNoDatabase = "kv/no-database"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -29,37 +52,84 @@ var rootCmd = &cobra.Command{
 You can use --encryption-key or --encryption-key-store-path to provide an AES key for encryption.
 If not provided, a key will be generated automatically and stored in a file.
 
-The database path can be customized with the --db flag or the KV_DB_PATH environment variable.`,
+The encryption key store can be protected by a passphrase (see "kv keys passwd").
+The passphrase is read from KV_PASSPHRASE, --passphrase-file or asked interactively.
+When KV_AGENT_SOCK points to a running agent (see "kv agent start") for the same key store,
+values are encrypted and decrypted by the agent and the key store is not read.
+
+The database path can be customized with the --db flag or the KV_DB_PATH environment variable.
+Only one kv process can write to the database at a time, other processes wait for the lock
+up to --lock-timeout. The get, list and render commands open the database read-only
+and can run in parallel with each other.
+
+Settings can be grouped in named profiles in ~/.config/kv/config.yaml and in a per-project
+.kv.yaml file, found in the working directory or one of its parents. A profile is selected
+with --profile or KV_PROFILE, or by the "profile" setting of the config files.
+Flags and environment variables override profile settings.
+
+With --output json every command prints its result as a JSON object and errors as
+{"error": {"code": ..., "exit_code": ..., "message": ...}} to STDERR.
+Exit codes:
+  0  success
+  1  other error
+  2  invalid command, arguments or flags
+  3  key or version not found
+  4  key is expired
+  5  bucket not found
+  6  decryption failed (wrong encryption key or passphrase, modified value)
+  7  invalid key name or encryption key
+  8  database is locked by another process
+  9  key or parameter exists with another value, or a restore target exists
+  10 integrity check found problems (see "kv doctor")`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		k, ks, err := loadAllKeys(
-			viper.GetString("encryption-key-store"), // path to keys.yaml
-			viper.GetString("encryption-key"),       // override encryption key if set via cli flag
-		)
-		if err != nil {
-			return fmt.Errorf("load keys: %w", err)
+		// a command may have a --json flag as a shorthand for --output json
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			outputFormat = outputJSON
+		}
+		if outputFormat != outputText && outputFormat != outputJSON {
+			return fmt.Errorf("invalid output format %q, expected text or json", outputFormat)
+		}
+		commandStarted = true
+		if configErr != nil {
+			return fmt.Errorf("load config: %w", configErr)
 		}
-		encryptionKeys = k
-		encryptionKenStore = ks
 
-		kvdb, err = bbolt.Open(viper.GetString("db"), 0o600, nil)
-		if err != nil {
-			return fmt.Errorf("open database: %w", err)
+		if !hasAnnotation(cmd, annotationNoKeys) {
+			if !hasAnnotation(cmd, annotationNoAgent) && viper.GetString("encryption-key") == "" {
+				agentClient = dialAgent(viper.GetString("encryption-key-store"))
+			}
+			if agentClient == nil {
+				k, ks, err := loadAllKeys(
+					viper.GetString("encryption-key-store"), // path to keys.yaml
+					viper.GetString("encryption-key"),       // override encryption key if set via cli flag
+				)
+				if err != nil {
+					return fmt.Errorf("load keys: %w", err)
+				}
+				encryptionKeys = k
+				encryptionKenStore = ks
+			}
 		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			cmd.Help()
-			os.Exit(0)
+
+		if hasAnnotation(cmd, annotationNoDatabase) {
+			return nil
 		}
+		var err error
+		kvdb, err = storage.Open(viper.GetString("db"), viper.GetDuration("lock-timeout"), hasAnnotation(cmd, annotationReadOnly))
+		return err
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		os.Exit(printError(cmd, err))
 	}
 }
 
@@ -68,26 +138,94 @@ func init() {
 	rootCmd.PersistentFlags().String("encryption-key", "", "encryption key (can also use KV_ENCRYPTION_KEY)")
 	rootCmd.PersistentFlags().String("encryption-key-store-path", expandPath("~/.kv.key"), "path to encryption key file (can also use KV_ENCRYPTION_KEY_STORE_PATH)")
 	rootCmd.PersistentFlags().StringP("bucket", "b", storage.DefaultBucket, "default bucket name (can also use KV_BUCKET)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "output format [text, json] (can also use KV_OUTPUT)")
+	rootCmd.PersistentFlags().String("passphrase-file", "", "path to a file with the encryption key store passphrase (can also use KV_PASSPHRASE_FILE, or KV_PASSPHRASE with the passphrase)")
+	rootCmd.PersistentFlags().Duration("lock-timeout", 10*time.Second, "how long to wait for the database lock held by another kv process, 0 waits forever (can also use KV_LOCK_TIMEOUT)")
+	rootCmd.PersistentFlags().String("profile", "", "configuration profile name (can also use KV_PROFILE)")
 
 	viper.BindPFlag("db", rootCmd.PersistentFlags().Lookup("db"))
 	viper.BindPFlag("encryption-key", rootCmd.PersistentFlags().Lookup("encryption-key"))
 	viper.BindPFlag("encryption-key-store", rootCmd.PersistentFlags().Lookup("encryption-key-store-path"))
 	viper.BindPFlag("bucket", rootCmd.PersistentFlags().Lookup("bucket"))
+	viper.BindPFlag("passphrase-file", rootCmd.PersistentFlags().Lookup("passphrase-file"))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("lock-timeout", rootCmd.PersistentFlags().Lookup("lock-timeout"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
 
 	viper.BindEnv("db", "KV_DB_PATH")
 	viper.BindEnv("encryption-key", "KV_ENCRYPTION_KEY")
-	viper.BindEnv("encryption-key-store-path", "KV_ENCRYPTION_KEY_STORE_PATH")
+	viper.BindEnv("encryption-key-store", "KV_ENCRYPTION_KEY_STORE_PATH")
 	viper.BindEnv("bucket", "KV_BUCKET")
+	viper.BindEnv("passphrase-file", "KV_PASSPHRASE_FILE")
+	viper.BindEnv("output", "KV_OUTPUT")
+	viper.BindEnv("lock-timeout", "KV_LOCK_TIMEOUT")
+	viper.BindEnv("profile", "KV_PROFILE")
 
 	cobra.OnInitialize(func() {
 		viper.AutomaticEnv()
 		_ = viper.BindPFlags(rootCmd.PersistentFlags())
 
+		// profile settings are defaults, flags and environment variables override them
+		profile, configErr = loadProfile(viper.GetString("profile"))
+		setDefault("db", profile.DB)
+		setDefault("encryption-key-store", profile.EncryptionKeyStore)
+		setDefault("passphrase-file", profile.PassphraseFile)
+		setDefault("bucket", profile.Bucket)
+		setDefault("output", profile.Output)
+		setDefault("lock-timeout", profile.LockTimeout)
+
 		// bucket name value
 		bucketName = viper.GetString("bucket")
+		outputFormat = viper.GetString("output")
 	})
 }
 
+// hasAnnotation reports whether the command or one of its parents has the annotation.
+func hasAnnotation(cmd *cobra.Command, annotation string) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[annotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadProfile reads the user and project config files and returns the profile by name,
+// or the default profile of the user config file for an empty name.
+// A profile with settings from a project file is reported on stderr.
+func loadProfile(name string) (config.Profile, error) {
+	cfg, err := config.Load(config.UserPath())
+	if err != nil {
+		return config.Profile{}, err
+	}
+	var project string
+	if wd, err := os.Getwd(); err == nil {
+		project = config.FindProject(wd)
+	}
+	if err := cfg.LoadProject(project); err != nil {
+		return config.Profile{}, err
+	}
+
+	p, err := cfg.Profile(name)
+	if err != nil {
+		return config.Profile{}, err
+	}
+	if cfg.FromProject(name) {
+		if name == "" {
+			name = cfg.Default
+		}
+		fmt.Fprintf(os.Stderr, "using settings of profile '%s' from %s\n", name, project)
+	}
+	return p, nil
+}
+
+// setDefault sets a default value of a setting if the value is not empty.
+func setDefault(key, value string) {
+	if value != "" {
+		viper.SetDefault(key, value)
+	}
+}
+
 func expandPath(path string) string {
 	if strings.HasPrefix(path, "~") {
 		if home, err := os.UserHomeDir(); err == nil {
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/server"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen    string
+	serveTokenFile string
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the store over a local HTTP/JSON API.",
+	Long: `This command starts an HTTP/JSON API over the store for tools that read keys often.
+The database and encryption keys are opened once and kept open while the server runs.
+
+Endpoints:
+  GET    /health
+  GET    /v1/buckets
+  GET    /v1/buckets/{bucket}/keys?values=true&tag=team=payments
+  GET    /v1/buckets/{bucket}/keys/{key}?version=N
+  PUT    /v1/buckets/{bucket}/keys/{key}    {"value": "...", "description": "...", "tags": {...}, "expires_at": "..."}
+  DELETE /v1/buckets/{bucket}/keys/{key}
+
+The token is read from KV_SERVE_TOKEN or --token-file. Requests must send it in the
+"Authorization: Bearer <token>" header. A TCP listener requires a token,
+a unix socket is created with 0600 permissions and the token is optional.
+
+The database is locked while the server runs, so other kv commands on the same
+database wait until it is stopped.`,
+	Example: `
+  kv serve --listen unix:///run/user/1000/kv.sock
+  curl --unix-socket /run/user/1000/kv.sock http://kv/v1/buckets/prod/keys/token
+  KV_SERVE_TOKEN=secret kv serve --listen 127.0.0.1:8200
+  curl -H "Authorization: Bearer secret" http://127.0.0.1:8200/v1/buckets/prod/keys`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoAgent: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := readServeToken()
+		if err != nil {
+			return fmt.Errorf("serve: failed: %w", err)
+		}
+		if token == "" && !server.IsUnix(serveListen) {
+			return errors.New("serve: failed: a TCP listener requires a token, set KV_SERVE_TOKEN or use --token-file")
+		}
+
+		l, err := server.Listen(serveListen)
+		if err != nil {
+			return fmt.Errorf("serve: failed: %w", err)
+		}
+
+		keyFn := func(bucket string) (string, error) {
+			return selectKey(encryptionKeys, bucket)
+		}
+		srv := &http.Server{
+			Handler:           server.New(kvdb, keyFn, token).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// Serve returns as soon as Shutdown starts, shutdownDone is closed when the
+		// handlers in flight are finished and the database can be closed
+		shutdownDone := make(chan struct{})
+		go func() {
+			defer close(shutdownDone)
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				_ = srv.Close()
+			}
+		}()
+
+		fmt.Fprintf(os.Stderr, "serve: listening on %s\n", serveListen)
+		serveErr := srv.Serve(l)
+		stop()
+		<-shutdownDone
+		if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			_ = kvdb.Close()
+			return fmt.Errorf("serve: failed: %w", serveErr)
+		}
+		return kvdb.Close()
+	},
+}
+
+// readServeToken returns the API token from the KV_SERVE_TOKEN environment variable
+// or the --token-file file. The token is empty when neither is set.
+func readServeToken() (string, error) {
+	if t := os.Getenv("KV_SERVE_TOKEN"); t != "" {
+		return t, nil
+	}
+	if serveTokenFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(serveTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	t := strings.TrimSpace(string(data))
+	if t == "" {
+		return "", fmt.Errorf("token file %s is empty", serveTokenFile)
+	}
+	return t, nil
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "unix://"+expandPath("~/.kv.sock"), "listen address: unix:///path/to/socket, tcp://host:port or host:port")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "path to a file with the API token (can also use KV_SERVE_TOKEN with the token)")
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+const (
+	syncPull = "pull"
+	syncPush = "push"
+)
+
+var (
+	syncDirection  string
+	syncPrune      bool
+	syncDryRun     bool
+	syncShowValues bool
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Synchronize a bucket with an external store",
+	Long: `The sync command applies only the differences between a bucket and an external store.
+
+With --direction pull the bucket is updated from the external store,
+with --direction push the external store is updated from the bucket.
+Keys missing from the source are deleted from the target only with --prune.
+
+Changes are shown for the target:
+  + key  added
+  - key  deleted
+  ~ key  updated`,
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.PersistentFlags().StringVar(&syncDirection, "direction", "", "Sync direction [pull, push]")
+	syncCmd.PersistentFlags().BoolVar(&syncPrune, "prune", false, "Delete keys missing from the source")
+	syncCmd.PersistentFlags().BoolVarP(&syncDryRun, "dry-run", "d", false, "Show changes without applying them")
+	syncCmd.PersistentFlags().BoolVarP(&syncShowValues, "values", "v", false, "Show key values")
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	awsSsm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+	"github.com/yousysadmin/kv/internal/diff"
+	"github.com/yousysadmin/kv/internal/importer/amazon"
+	"github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+var (
+	syncSsmAws      amazon.Flags
+	syncSsmKMSKeyID string
+)
+
+// syncSsmCmd represents the sync ssm command
+var syncSsmCmd = &cobra.Command{
+	Use:   "ssm <path>",
+	Short: "Synchronize a bucket with an AWS SSM Parameter Store path",
+	Long: `Apply the differences between keys of the bucket and parameters under the path,
+a parameter <path>/<key> matches the key <key>. Parameters are written as SecureString.
+
+With --direction pull the bucket is updated from SSM,
+with --direction push SSM is updated from the bucket.
+Keys missing from the source are deleted from the target only with --prune,
+keys deleted from the bucket lose their history.
+Expired keys are treated as missing from the bucket.`,
+	Example: `
+  # Show what would change in the bucket
+  kv sync ssm --bucket=prod --direction pull --dry-run /prod/app/
+
+  # Update the bucket from SSM and delete keys that are not in SSM
+  kv sync ssm --bucket=prod --direction pull --prune /prod/app/
+
+  # Update SSM from the bucket
+  kv sync ssm --bucket=prod --direction push --kms-key-id=alias/kv /prod/app/`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+
+		if syncDirection != syncPull && syncDirection != syncPush {
+			return errors.New("sync with ssm failed: --direction must be pull or push")
+		}
+
+		local, err := bucketSecrets(bucketName)
+		// a bucket is created on pull, but never treated as empty on push to not prune everything
+		if errors.Is(err, storage.ErrBucketNotFound) && syncDirection == syncPull {
+			local, err = map[string]string{}, nil
+		}
+		if err != nil {
+			return fmt.Errorf("sync with ssm failed: %w", err)
+		}
+
+		ctx := cmd.Context()
+		cfg, err := syncSsmAws.Config(ctx)
+		if err != nil {
+			return fmt.Errorf("sync with ssm failed: %w", err)
+		}
+		client := awsSsm.NewFromConfig(cfg)
+		remote, err := ssm.GetKeys(ctx, client, path)
+		if err != nil {
+			return fmt.Errorf("sync with ssm failed: %w", err)
+		}
+
+		var changes []diff.Change
+		if syncDirection == syncPull {
+			changes = diff.Compare(local, remote)
+			if !syncPrune {
+				changes = slices.DeleteFunc(changes, func(c diff.Change) bool { return c.Kind == diff.Removed })
+			}
+			if !syncDryRun && len(changes) > 0 {
+				err = pullChanges(bucketName, changes)
+			}
+		} else {
+			changes, err = pushChanges(ctx, client, path, local, remote)
+		}
+		if err != nil {
+			return fmt.Errorf("sync with ssm failed: %w", err)
+		}
+
+		res := map[string]any{
+			"direction": syncDirection,
+			"dry_run":   syncDryRun,
+			"changes":   changesResult(changes, syncShowValues),
+		}
+		if len(changes) == 0 {
+			return printResult(res, func() { fmt.Println("No differences.") })
+		}
+
+		return printResult(res, func() {
+			printChanges(changes, syncShowValues)
+			if syncDryRun {
+				fmt.Printf("DryRun: %d changes.\n", len(changes))
+			} else {
+				fmt.Printf("Synced %d changes.\n", len(changes))
+			}
+		})
+	},
+}
+
+// pullChanges applies changes to a bucket in a single transaction, either all changes
+// are applied or none is.
+func pullChanges(bucket string, changes []diff.Change) error {
+	s, err := bucketStorage(bucket)
+	if err != nil {
+		return err
+	}
+	var entities []models.Entity
+	var deletes []string
+	for _, c := range changes {
+		if c.Kind == diff.Removed {
+			deletes = append(deletes, c.Key)
+		} else {
+			entities = append(entities, models.Entity{Key: c.Key, Value: c.New})
+		}
+	}
+	_, err = s.AddBatch(bucket, entities, storage.BatchOptions{OnConflict: storage.ConflictOverwrite, Delete: deletes})
+	return err
+}
+
+// pushChanges writes the bucket keys to SSM and returns the changes PutSecrets made,
+// or would make with --dry-run. Old values are taken from remote.
+func pushChanges(ctx context.Context, client ssm.Client, path string, local, remote map[string]string) ([]diff.Change, error) {
+	applied, err := ssm.PutSecrets(ctx, client, path, local, ssm.ExportOptions{
+		KMSKeyID:  syncSsmKMSKeyID,
+		Overwrite: true,
+		Prune:     syncPrune,
+		DryRun:    syncDryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var changes []diff.Change
+	for _, c := range applied {
+		switch c.Action {
+		case ssm.ActionCreate:
+			changes = append(changes, diff.Change{Key: c.Key, Kind: diff.Added, New: local[c.Key]})
+		case ssm.ActionUpdate:
+			changes = append(changes, diff.Change{Key: c.Key, Kind: diff.Changed, Old: remote[c.Key], New: local[c.Key]})
+		case ssm.ActionDelete:
+			key := ssm.KeyName(path, c.Name)
+			changes = append(changes, diff.Change{Key: key, Kind: diff.Removed, Old: remote[key]})
+		}
+	}
+	return changes, nil
+}
+
+func init() {
+	syncCmd.AddCommand(syncSsmCmd)
+
+	syncSsmAws.Register(syncSsmCmd.PersistentFlags())
+	syncSsmCmd.PersistentFlags().StringVar(&syncSsmKMSKeyID, "kms-key-id", "", "KMS key ID, ARN or alias to encrypt parameters on push (AWS managed key if not set)")
+}
@@ -10,8 +10,10 @@ import (
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("kv: %s\n", pkg.Version)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printResult(map[string]string{"version": pkg.Version}, func() {
+			fmt.Printf("kv: %s\n", pkg.Version)
+		})
 	},
 }
 
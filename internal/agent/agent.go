@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+const defaultBucket = "default"
+
+var (
+	ErrNoKey = errors.New("no key for the bucket and no default key")
+	// ErrStale is returned when the key store was changed after the agent loaded it.
+	ErrStale = errors.New("key store was changed, the agent is stopped")
+)
+
+// Error codes of responses, a client maps them back to errors.
+const (
+	codeIntegrity     = "integrity"
+	codeDecryptFailed = "decrypt_failed"
+	codeNoKey         = "no_key"
+	codeStale         = "stale"
+)
+
+// request is a request to the agent.
+type request struct {
+	Op     string `json:"op"`
+	Bucket string `json:"bucket,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+// response is a response of the agent.
+type response struct {
+	Value  string  `json:"value,omitempty"`
+	Status *Status `json:"status,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Status describes a running agent.
+type Status struct {
+	PID          int       `json:"pid"`
+	KeyStore     string    `json:"key_store"`
+	Buckets      []string  `json:"buckets"`
+	MemoryLocked bool      `json:"memory_locked"`
+	IdleTimeout  string    `json:"idle_timeout"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// Agent holds encryption keys and serves encryption requests.
+type Agent struct {
+	storePath string
+	storeStat os.FileInfo
+	idle      time.Duration
+	started   time.Time
+
+	mu   sync.RWMutex // guards keys and mem, a wiped agent has no keys
+	mem  *lockedBuffer
+	keys map[string][]byte // raw keys, slices of mem
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+	conns    sync.WaitGroup
+}
+
+// New creates an agent with keys loaded from the key store file at storePath.
+// The keys are read from r in the format written by EncodeKeys directly to locked memory.
+// With a zero idle timeout the agent runs until stopped.
+func New(storePath string, r io.Reader, idle time.Duration) (*Agent, error) {
+	fi, err := os.Stat(storePath)
+	if err != nil {
+		return nil, fmt.Errorf("key store: %w", err)
+	}
+	mem, keys, err := readKeys(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Agent{
+		storePath: storePath,
+		storeStat: fi,
+		idle:      idle,
+		started:   time.Now(),
+		mem:       mem,
+		keys:      keys,
+		stopped:   make(chan struct{}),
+	}, nil
+}
+
+// Serve accepts connections on l until the agent is stopped, then wipes the keys.
+// It returns nil when the agent is stopped by a request, the idle timeout or a changed key store.
+func (a *Agent) Serve(l net.Listener) error {
+	defer a.wipe()
+
+	var timer *time.Timer
+	if a.idle > 0 {
+		timer = time.AfterFunc(a.idle, a.Stop)
+		defer timer.Stop()
+	}
+	go func() {
+		<-a.stopped
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-a.stopped:
+				a.conns.Wait()
+				return nil
+			default:
+			}
+			a.Stop()
+			a.conns.Wait()
+			return err
+		}
+		a.conns.Add(1)
+		go func() {
+			defer a.conns.Done()
+			a.handle(conn, timer)
+		}()
+	}
+}
+
+// Stop stops the agent, Serve wipes the keys and returns.
+func (a *Agent) Stop() {
+	a.stopOnce.Do(func() { close(a.stopped) })
+}
+
+// handle answers requests of a connection until it is closed or the agent is stopped.
+func (a *Agent) handle(conn net.Conn, timer *time.Timer) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-a.stopped:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				_ = enc.Encode(response{Error: fmt.Sprintf("invalid request: %v", err)})
+			}
+			return
+		}
+		if timer != nil {
+			timer.Reset(a.idle)
+		}
+		resp := a.do(req)
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+		if req.Op == "stop" || resp.Code == codeStale {
+			a.Stop()
+			return
+		}
+	}
+}
+
+// do executes a request.
+func (a *Agent) do(req request) response {
+	if err := a.checkStore(); err != nil {
+		return errorResponse(err)
+	}
+
+	switch req.Op {
+	case "status":
+		return response{Status: a.status()}
+	case "stop":
+		return response{}
+	case "seal", "open":
+		// the cipher uses the key in locked memory, it must not be wiped meanwhile
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		c, err := a.cipher(req.Bucket)
+		if err != nil {
+			return errorResponse(err)
+		}
+		var v string
+		if req.Op == "seal" {
+			v, err = c.Seal(req.Bucket, req.Key, req.Value)
+		} else {
+			v, err = c.Open(req.Bucket, req.Key, req.Value)
+		}
+		if err != nil {
+			return errorResponse(err)
+		}
+		return response{Value: v}
+	}
+	return response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+}
+
+// checkStore returns ErrStale if the key store file was changed or removed since it was loaded.
+func (a *Agent) checkStore() error {
+	fi, err := os.Stat(a.storePath)
+	if err != nil || !fi.ModTime().Equal(a.storeStat.ModTime()) || fi.Size() != a.storeStat.Size() {
+		return ErrStale
+	}
+	return nil
+}
+
+// cipher returns the cipher with the bucket key or the default key, a.mu must be held
+// while the cipher is used.
+func (a *Agent) cipher(bucket string) (storage.Cipher, error) {
+	if k := a.keys[bucket]; len(k) > 0 {
+		return storage.RawKeyCipher(k), nil
+	}
+	if k := a.keys[defaultBucket]; len(k) > 0 {
+		return storage.RawKeyCipher(k), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNoKey, bucket)
+}
+
+// status returns the agent status.
+func (a *Agent) status() *Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	idle := "none"
+	if a.idle > 0 {
+		idle = a.idle.String()
+	}
+	return &Status{
+		PID:          os.Getpid(),
+		KeyStore:     a.storePath,
+		Buckets:      slices.Sorted(maps.Keys(a.keys)),
+		MemoryLocked: a.mem != nil && a.mem.locked,
+		IdleTimeout:  idle,
+		StartedAt:    a.started,
+	}
+}
+
+// wipe clears and releases the keys.
+func (a *Agent) wipe() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.mem != nil {
+		a.mem.free()
+	}
+	a.mem = nil
+	a.keys = nil
+}
+
+// errorResponse returns a response for an error with the code of known errors.
+func errorResponse(err error) response {
+	resp := response{Error: err.Error()}
+	switch {
+	case errors.Is(err, storage.ErrIntegrity):
+		resp.Code = codeIntegrity
+	case errors.Is(err, encrypt.ErrorDecryptionFailed):
+		resp.Code = codeDecryptFailed
+	case errors.Is(err, ErrNoKey):
+		resp.Code = codeNoKey
+	case errors.Is(err, ErrStale):
+		resp.Code = codeStale
+	}
+	return resp
+}
@@ -0,0 +1,180 @@
+package agent_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/agent"
+	"github.com/yousysadmin/kv/internal/enckeystore"
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func mustGenKey(t *testing.T) string {
+	t.Helper()
+	k, err := enckeystore.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(k)
+}
+
+// startAgent starts an agent on a temporary socket and returns a connected client,
+// the key store path and the channel with the Serve result.
+func startAgent(t *testing.T, keys map[string]string, idle time.Duration) (*agent.Client, string, <-chan error) {
+	t.Helper()
+	dir := t.TempDir()
+	store := filepath.Join(dir, "keys.yaml")
+	if err := os.WriteFile(store, []byte("keys"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := agent.EncodeKeys(&buf, keys); err != nil {
+		t.Fatalf("EncodeKeys: %v", err)
+	}
+	a, err := agent.New(store, &buf, idle)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(l) }()
+	t.Cleanup(a.Stop)
+
+	c, err := agent.Dial(sock)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, store, done
+}
+
+func waitStopped(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent is not stopped")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	def, prod := mustGenKey(t), mustGenKey(t)
+	c, store, _ := startAgent(t, map[string]string{"default": def, "prod": prod}, 0)
+
+	enc, err := c.Seal("prod", "token", "secret")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	// values are compatible with the bucket key
+	if v, err := storage.KeyCipher(prod).Open("prod", "token", enc); err != nil || v != "secret" {
+		t.Errorf("Open with bucket key = %q, %v", v, err)
+	}
+	if v, err := c.Open("prod", "token", enc); err != nil || v != "secret" {
+		t.Errorf("Open = %q, %v", v, err)
+	}
+
+	// a bucket without a key uses the default key
+	enc, err = storage.KeyCipher(def).Seal("stage", "token", "stage-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Open("stage", "token", enc); err != nil || v != "stage-secret" {
+		t.Errorf("Open with default key = %q, %v", v, err)
+	}
+
+	// a value moved to another key is rejected with a matching error
+	if _, err := c.Open("stage", "password", enc); !errors.Is(err, storage.ErrIntegrity) {
+		t.Errorf("Open moved value: expected ErrIntegrity, got: %v", err)
+	}
+
+	st, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.KeyStore != store || len(st.Buckets) != 2 || st.PID != os.Getpid() || st.IdleTimeout != "none" {
+		t.Errorf("unexpected status: %+v", st)
+	}
+}
+
+func TestEncodedKeys(t *testing.T) {
+	// bucket names are quoted, legacy and hex keys are passed as raw bytes
+	legacy := "0123456789abcdef0123456789abcdef"
+	keys := map[string]string{"with space \"and\"\nnewline": mustGenKey(t), "legacy": legacy}
+	c, _, _ := startAgent(t, keys, 0)
+
+	enc, err := storage.KeyCipher(legacy).Seal("legacy", "token", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Open("legacy", "token", enc); err != nil || v != "secret" {
+		t.Errorf("Open with legacy key = %q, %v", v, err)
+	}
+	st, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(st.Buckets) != 2 || st.Buckets[1] != "with space \"and\"\nnewline" {
+		t.Errorf("unexpected buckets: %q", st.Buckets)
+	}
+
+	store := filepath.Join(t.TempDir(), "keys.yaml")
+	if err := os.WriteFile(store, []byte("keys"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []string{"", "99999999\n", "5\nab", "10\n\"prod\" AAAA\n", "9\nprod AAAA\n"} {
+		if _, err := agent.New(store, strings.NewReader(in), 0); err == nil {
+			t.Errorf("New(%q): expected an error", in)
+		}
+	}
+}
+
+func TestNoKey(t *testing.T) {
+	c, _, _ := startAgent(t, map[string]string{"prod": mustGenKey(t)}, 0)
+	if _, err := c.Seal("stage", "token", "v"); !errors.Is(err, agent.ErrNoKey) {
+		t.Errorf("Seal: expected ErrNoKey, got: %v", err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	c, _, done := startAgent(t, map[string]string{"default": mustGenKey(t)}, 0)
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	waitStopped(t, done)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	c, _, done := startAgent(t, map[string]string{"default": mustGenKey(t)}, 200*time.Millisecond)
+	if _, err := c.Status(); err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	waitStopped(t, done)
+	if _, err := c.Status(); err == nil {
+		t.Error("Status after idle timeout: expected error")
+	}
+}
+
+func TestStaleKeyStore(t *testing.T) {
+	c, store, done := startAgent(t, map[string]string{"default": mustGenKey(t)}, 0)
+	if err := os.WriteFile(store, []byte("rotated keys"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Seal("prod", "token", "v"); !errors.Is(err, agent.ErrStale) {
+		t.Errorf("Seal: expected ErrStale, got: %v", err)
+	}
+	waitStopped(t, done)
+}
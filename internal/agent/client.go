@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+const (
+	dialTimeout    = time.Second
+	requestTimeout = 30 * time.Second
+)
+
+// Client is a connection to an agent, it implements storage.Cipher.
+// A Client is safe for concurrent use.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+// Dial connects to the agent listening on a unix socket.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, enc: json.NewEncoder(conn), dec: json.NewDecoder(conn)}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Seal encrypts a value of the key in the bucket.
+func (c *Client) Seal(bucket, key, value string) (string, error) {
+	resp, err := c.call(request{Op: "seal", Bucket: bucket, Key: key, Value: value})
+	return resp.Value, err
+}
+
+// Open decrypts a value of the key in the bucket.
+func (c *Client) Open(bucket, key, encValue string) (string, error) {
+	resp, err := c.call(request{Op: "open", Bucket: bucket, Key: key, Value: encValue})
+	return resp.Value, err
+}
+
+// Status returns the agent status.
+func (c *Client) Status() (Status, error) {
+	resp, err := c.call(request{Op: "status"})
+	if err != nil {
+		return Status{}, err
+	}
+	if resp.Status == nil {
+		return Status{}, errors.New("agent: empty status")
+	}
+	return *resp.Status, nil
+}
+
+// Stop stops the agent.
+func (c *Client) Stop() error {
+	_, err := c.call(request{Op: "stop"})
+	return err
+}
+
+// call sends a request and reads its response.
+func (c *Client) call(req request) (response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.conn.SetDeadline(time.Now().Add(requestTimeout))
+	var resp response
+	if err := c.enc.Encode(req); err != nil {
+		return resp, err
+	}
+	if err := c.dec.Decode(&resp); err != nil {
+		return resp, err
+	}
+	if resp.Error != "" {
+		return resp, &remoteError{code: resp.Code, msg: resp.Error}
+	}
+	return resp, nil
+}
+
+// remoteError is an error returned by the agent.
+type remoteError struct {
+	code string
+	msg  string
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+// Is matches the error returned by the agent.
+func (e *remoteError) Is(target error) bool {
+	switch e.code {
+	case codeIntegrity:
+		return target == storage.ErrIntegrity
+	case codeDecryptFailed:
+		return target == encrypt.ErrorDecryptionFailed
+	case codeNoKey:
+		return target == ErrNoKey
+	case codeStale:
+		return target == ErrStale
+	}
+	return false
+}
+
+// compile-time check that Client implements storage.Cipher.
+var _ storage.Cipher = (*Client)(nil)
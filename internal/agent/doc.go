@@ -0,0 +1,27 @@
+/*
+Package agent implements kv-agent, a background process that holds the unlocked
+encryption keys in memory and encrypts and decrypts values for kv commands.
+
+The agent answers requests over a unix socket created with 0600 permissions.
+Each request and response is a JSON object on a single line:
+
+	{"op": "status"}
+	{"op": "seal", "bucket": "prod", "key": "token", "value": "secret"}
+	{"op": "open", "bucket": "prod", "key": "token", "value": "v2:..."}
+	{"op": "stop"}
+
+	{"value": "..."}
+	{"status": {"pid": 42, "key_store": "/home/user/.kv.key", ...}}
+	{"error": "...", "code": "integrity"}
+
+Values are encrypted with the bucket key, or the default key for buckets without one,
+exactly like storage.KeyCipher does, so values written through the agent can be read
+without it and the other way around.
+
+The keys are passed to the agent as raw bytes (see EncodeKeys) and decoded directly
+to memory locked with mlock(2) where the platform allows it. They are used in place
+for every request, never copied to the Go heap, and are wiped when the agent stops. The agent stops on the "stop" request, after the idle
+timeout without requests, or when the key store file is changed, so it never serves
+keys that were rotated or removed.
+*/
+package agent
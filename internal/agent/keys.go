@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+// maxKeysSize limits the size of the encoded keys read by New.
+const maxKeysSize = 1 << 20
+
+var errInvalidKeys = errors.New("invalid encoded keys")
+
+// EncodeKeys writes keys by bucket name in the format read by New:
+//
+//	<size of the lines in bytes>\n
+//	<quoted bucket name> <base64 raw key>\n
+//	...
+//
+// Keys are decoded to raw bytes first, the buffers with raw and encoded keys are wiped.
+func EncodeKeys(w io.Writer, keys map[string]string) error {
+	var buf []byte
+	defer func() { clear(buf) }()
+	for b, k := range keys {
+		raw, err := encrypt.DecodeAESKey(k)
+		if err != nil {
+			return fmt.Errorf("key for bucket %q: %w", b, err)
+		}
+		buf = strconv.AppendQuote(buf, b)
+		buf = append(buf, ' ')
+		buf = base64.StdEncoding.AppendEncode(buf, raw)
+		buf = append(buf, '\n')
+		clear(raw)
+	}
+	if _, err := fmt.Fprintf(w, "%d\n", len(buf)); err != nil {
+		return err
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readKeys reads keys written by EncodeKeys from r to locked memory.
+// The encoded keys are read to the first half of the buffer and wiped after the raw keys
+// are decoded to the second half, so the keys are never copied to the Go heap.
+func readKeys(r io.Reader) (*lockedBuffer, map[string][]byte, error) {
+	size, err := readSize(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	mem := newLockedBuffer(2 * size)
+	keys, err := decodeKeys(r, mem.data[:size], mem.data[size:])
+	if err != nil {
+		mem.free()
+		return nil, nil, err
+	}
+	return mem, keys, nil
+}
+
+// decodeKeys reads the encoded keys to text and decodes the raw keys to raw.
+// The text is wiped, the returned keys are slices of raw.
+func decodeKeys(r io.Reader, text, raw []byte) (map[string][]byte, error) {
+	defer clear(text)
+	if _, err := io.ReadFull(r, text); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidKeys, err)
+	}
+
+	keys := make(map[string][]byte)
+	var off int
+	for line := range bytes.Lines(text) {
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		i := bytes.LastIndexByte(line, ' ')
+		if i < 0 {
+			return nil, errInvalidKeys
+		}
+		bucket, err := strconv.Unquote(string(line[:i]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: bucket name: %v", errInvalidKeys, err)
+		}
+		n, err := base64.StdEncoding.Decode(raw[off:], line[i+1:])
+		if err != nil || !encrypt.AESKeySize(n).IsValid() {
+			return nil, fmt.Errorf("%w: key for bucket %q", errInvalidKeys, bucket)
+		}
+		keys[bucket] = raw[off : off+n : off+n]
+		off += n
+	}
+	return keys, nil
+}
+
+// readSize reads the "<size>\n" line byte by byte, so no key bytes are buffered.
+func readSize(r io.Reader) (int, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) <= len(strconv.Itoa(maxKeysSize)) {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return 0, fmt.Errorf("%w: %v", errInvalidKeys, err)
+		}
+		if b[0] == '\n' {
+			size, err := strconv.Atoi(string(line))
+			if err != nil || size < 0 || size > maxKeysSize {
+				return 0, fmt.Errorf("%w: size %q", errInvalidKeys, line)
+			}
+			return size, nil
+		}
+		line = append(line, b[0])
+	}
+	return 0, fmt.Errorf("%w: size is too long", errInvalidKeys)
+}
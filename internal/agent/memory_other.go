@@ -0,0 +1,20 @@
+//go:build !unix
+
+package agent
+
+// lockedBuffer is memory for keys, memory locking is not supported on this platform.
+type lockedBuffer struct {
+	data   []byte
+	locked bool
+}
+
+// newLockedBuffer allocates size bytes.
+func newLockedBuffer(size int) *lockedBuffer {
+	return &lockedBuffer{data: make([]byte, size)}
+}
+
+// free wipes the memory.
+func (b *lockedBuffer) free() {
+	clear(b.data)
+	b.data = nil
+}
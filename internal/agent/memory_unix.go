@@ -0,0 +1,35 @@
+//go:build unix
+
+package agent
+
+import "golang.org/x/sys/unix"
+
+// lockedBuffer is memory for keys that is not swapped to disk where possible.
+type lockedBuffer struct {
+	data   []byte
+	mapped bool
+	locked bool
+}
+
+// newLockedBuffer allocates size bytes outside of the Go heap and locks them with mlock(2).
+// It falls back to unlocked or heap memory when the system doesn't allow it.
+func newLockedBuffer(size int) *lockedBuffer {
+	data, err := unix.Mmap(-1, 0, max(size, 1), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return &lockedBuffer{data: make([]byte, size)}
+	}
+	return &lockedBuffer{data: data[:size], mapped: true, locked: unix.Mlock(data) == nil}
+}
+
+// free wipes and releases the memory.
+func (b *lockedBuffer) free() {
+	clear(b.data)
+	if b.mapped {
+		data := b.data[:cap(b.data)]
+		if b.locked {
+			_ = unix.Munlock(data)
+		}
+		_ = unix.Munmap(data)
+	}
+	b.data = nil
+}
@@ -0,0 +1,326 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+// FormatVersion is the version of the archive format written by Write.
+const FormatVersion = 1
+
+// Archive file names.
+const (
+	DBFile       = "kv.db"
+	KeyStoreFile = "keystore.sealed"
+	ManifestFile = "manifest.json"
+)
+
+// maxMetaSize limits the size of the manifest and the sealed key store read from an archive.
+const maxMetaSize = 1 << 20
+
+var (
+	ErrInvalidArchive     = errors.New("invalid backup archive")
+	ErrChecksum           = errors.New("backup archive checksum mismatch")
+	ErrUnsupportedVersion = errors.New("unsupported backup archive format version")
+	ErrNoKeyStore         = errors.New("backup archive doesn't include the key store")
+	ErrWrongPassphrase    = errors.New("wrong backup passphrase")
+)
+
+// Manifest describes the archive content.
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	KVVersion     string    `json:"kv_version,omitempty"`
+	Buckets       []string  `json:"buckets"`
+	Files         []File    `json:"files"`
+}
+
+// File is a file in the archive.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// HasKeyStore reports whether the archive includes the key store.
+func (m *Manifest) HasKeyStore() bool {
+	for _, f := range m.Files {
+		if f.Name == KeyStoreFile {
+			return true
+		}
+	}
+	return false
+}
+
+// Options of Write.
+type Options struct {
+	// KeyStore is the key store file content, it's included in the archive if set.
+	KeyStore []byte
+	// Passphrase seals the key store, it's required with KeyStore.
+	Passphrase []byte
+	// KVVersion is the kv version written to the manifest.
+	KVVersion string
+}
+
+// Write writes an archive with a consistent snapshot of the database to w.
+func Write(w io.Writer, db *bbolt.DB, opts Options) (*Manifest, error) {
+	if opts.KeyStore != nil && len(opts.Passphrase) == 0 {
+		return nil, errors.New("backup passphrase is required to include the key store")
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	m := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		KVVersion:     opts.KVVersion,
+		Buckets:       []string{},
+	}
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		err := tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			if storage.IsReservedBucket(string(name)) {
+				return nil
+			}
+			m.Buckets = append(m.Buckets, string(name))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		f, err := writeFile(tw, DBFile, tx.Size(), m.CreatedAt, func(w io.Writer) error {
+			_, err := tx.WriteTo(w)
+			return err
+		})
+		m.Files = append(m.Files, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("write database snapshot: %w", err)
+	}
+
+	if opts.KeyStore != nil {
+		box, err := seal(opts.Passphrase, opts.KeyStore)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(box)
+		if err != nil {
+			return nil, err
+		}
+		f, err := writeData(tw, KeyStoreFile, data, m.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("write key store: %w", err)
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writeData(tw, ManifestFile, data, m.CreatedAt); err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeFile writes a tar entry of size bytes written by write and returns its checksum.
+func writeFile(tw *tar.Writer, name string, size int64, mtime time.Time, write func(w io.Writer) error) (File, error) {
+	hdr := &tar.Header{Name: name, Mode: 0o600, Size: size, ModTime: mtime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return File{}, err
+	}
+	h := sha256.New()
+	if err := write(io.MultiWriter(tw, h)); err != nil {
+		return File{}, err
+	}
+	return File{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// writeData writes a tar entry with data and returns its checksum.
+func writeData(tw *tar.Writer, name string, data []byte, mtime time.Time) (File, error) {
+	return writeFile(tw, name, int64(len(data)), mtime, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Archive is an extracted and verified archive.
+type Archive struct {
+	Manifest Manifest
+	// DBPath is the path of the extracted database snapshot.
+	DBPath string
+
+	keyStore []byte // sealed key store
+}
+
+// Open extracts an archive from r to dir and verifies it against its manifest.
+func Open(r io.Reader, dir string) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	a := &Archive{DBPath: filepath.Join(dir, DBFile)}
+	var manifest []byte
+	sums := make(map[string]File)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if _, ok := sums[hdr.Name]; ok || hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: unexpected entry %q", ErrInvalidArchive, hdr.Name)
+		}
+
+		h := sha256.New()
+		var n int64
+		switch hdr.Name {
+		case DBFile:
+			n, err = extractFile(a.DBPath, io.TeeReader(tr, h))
+		case KeyStoreFile:
+			a.keyStore, err = readLimited(io.TeeReader(tr, h))
+			n = int64(len(a.keyStore))
+		case ManifestFile:
+			manifest, err = readLimited(tr)
+			continue
+		default:
+			return nil, fmt.Errorf("%w: unexpected entry %q", ErrInvalidArchive, hdr.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, hdr.Name, err)
+		}
+		sums[hdr.Name] = File{Name: hdr.Name, Size: n, SHA256: sum(h)}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, ManifestFile)
+	}
+	if err := json.Unmarshal(manifest, &a.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, ManifestFile, err)
+	}
+	if err := a.verify(sums); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// verify checks the manifest version and that the extracted files match the manifest.
+func (a *Archive) verify(sums map[string]File) error {
+	m := a.Manifest
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return fmt.Errorf("%w: %d (supported: %d)", ErrUnsupportedVersion, m.FormatVersion, FormatVersion)
+	}
+	if len(m.Files) != len(sums) {
+		return fmt.Errorf("%w: manifest lists %d files, archive has %d", ErrChecksum, len(m.Files), len(sums))
+	}
+	for _, f := range m.Files {
+		got, ok := sums[f.Name]
+		if !ok {
+			return fmt.Errorf("%w: %s is missing", ErrChecksum, f.Name)
+		}
+		if got != f {
+			return fmt.Errorf("%w: %s", ErrChecksum, f.Name)
+		}
+	}
+	if _, ok := sums[DBFile]; !ok {
+		return fmt.Errorf("%w: %s is missing", ErrInvalidArchive, DBFile)
+	}
+	return nil
+}
+
+// KeyStore returns the key store file content sealed in the archive.
+func (a *Archive) KeyStore(passphrase []byte) ([]byte, error) {
+	if a.keyStore == nil {
+		return nil, ErrNoKeyStore
+	}
+	var box encrypt.SealedBox
+	if err := json.Unmarshal(a.keyStore, &box); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, KeyStoreFile, err)
+	}
+	return open(&box, passphrase)
+}
+
+// extractFile writes r to a new file at path with 0600 permissions.
+func extractFile(path string, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return n, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// readLimited reads a small archive entry.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxMetaSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMetaSize {
+		return nil, errors.New("entry is too large")
+	}
+	return data, nil
+}
+
+func sum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// seal encrypts the key store with a key derived from the backup passphrase.
+func seal(passphrase, plain []byte) (*encrypt.SealedBox, error) {
+	box, err := encrypt.Seal(passphrase, plain, encrypt.DefaultScryptParams)
+	if err != nil {
+		return nil, fmt.Errorf("seal key store: %w", err)
+	}
+	return box, nil
+}
+
+// open decrypts the sealed key store with the backup passphrase.
+func open(box *encrypt.SealedBox, passphrase []byte) ([]byte, error) {
+	plain, err := box.Open(passphrase)
+	if errors.Is(err, encrypt.ErrorWrongPassphrase) {
+		return nil, ErrWrongPassphrase
+	}
+	if errors.Is(err, encrypt.ErrorInvalidScryptParams) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidArchive, KeyStoreFile, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open key store: %w", err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,278 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/backup"
+	"github.com/yousysadmin/kv/internal/enckeystore"
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+func openDB(t *testing.T, path string) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testDB returns a database with keys in the prod and stage buckets, prod/token has two versions.
+func testDB(t *testing.T) (*bbolt.DB, string) {
+	t.Helper()
+	db := openDB(t, filepath.Join(t.TempDir(), "kv.db"))
+	k, err := enckeystore.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := string(k)
+	s := storage.NewEntityStorage(db, key)
+	for _, kv := range [][3]string{{"prod", "token", "v1"}, {"prod", "token", "v2"}, {"stage", "token", "stage"}} {
+		if err := s.Add(kv[0], kv[1], kv[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, key
+}
+
+func TestWriteOpen(t *testing.T) {
+	db, key := testDB(t)
+	var buf bytes.Buffer
+	m, err := backup.Write(&buf, db, backup.Options{
+		KeyStore:   []byte("keys:\n  default: secret\n"),
+		Passphrase: []byte("backup-pass"),
+		KVVersion:  "test",
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !slices.Equal(m.Buckets, []string{"prod", "stage"}) || !m.HasKeyStore() {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	a, err := backup.Open(&buf, t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if a.Manifest.FormatVersion != backup.FormatVersion || a.Manifest.KVVersion != "test" {
+		t.Errorf("unexpected manifest: %+v", a.Manifest)
+	}
+
+	ks, err := a.KeyStore([]byte("backup-pass"))
+	if err != nil || string(ks) != "keys:\n  default: secret\n" {
+		t.Errorf("KeyStore = %q, %v", ks, err)
+	}
+	if _, err := a.KeyStore([]byte("wrong")); !errors.Is(err, backup.ErrWrongPassphrase) {
+		t.Errorf("KeyStore with wrong passphrase: expected ErrWrongPassphrase, got: %v", err)
+	}
+
+	// the snapshot has values and history
+	snap := openDB(t, a.DBPath)
+	s := storage.NewEntityStorage(snap, key)
+	if v, err := s.Get("prod", "token"); err != nil || v != "v2" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if v, err := s.GetVersion("prod", "token", 1); err != nil || v != "v1" {
+		t.Errorf("GetVersion = %q, %v", v, err)
+	}
+}
+
+func TestWriteWithoutKeyStore(t *testing.T) {
+	db, _ := testDB(t)
+	var buf bytes.Buffer
+	if _, err := backup.Write(&buf, db, backup.Options{}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	a, err := backup.Open(&buf, t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := a.KeyStore([]byte("pass")); !errors.Is(err, backup.ErrNoKeyStore) {
+		t.Errorf("KeyStore: expected ErrNoKeyStore, got: %v", err)
+	}
+
+	if _, err := backup.Write(&buf, db, backup.Options{KeyStore: []byte("keys")}); err == nil {
+		t.Error("Write key store without passphrase: expected error")
+	}
+}
+
+// rewrite copies an archive, changing entries with fn.
+func rewrite(t *testing.T, archive []byte, fn func(name string, data []byte) []byte) []byte {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		data = fn(hdr.Name, data)
+		hdr.Size = int64(len(data))
+		_ = tw.WriteHeader(hdr)
+		_, _ = tw.Write(data)
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	return out.Bytes()
+}
+
+func TestOpenVerifies(t *testing.T) {
+	db, _ := testDB(t)
+	var buf bytes.Buffer
+	if _, err := backup.Write(&buf, db, backup.Options{}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	archive := buf.Bytes()
+
+	corrupted := rewrite(t, archive, func(name string, data []byte) []byte {
+		if name == backup.DBFile {
+			data[len(data)-1] ^= 1
+		}
+		return data
+	})
+	if _, err := backup.Open(bytes.NewReader(corrupted), t.TempDir()); !errors.Is(err, backup.ErrChecksum) {
+		t.Errorf("Open corrupted: expected ErrChecksum, got: %v", err)
+	}
+
+	future := rewrite(t, archive, func(name string, data []byte) []byte {
+		if name == backup.ManifestFile {
+			return bytes.Replace(data, []byte(`"format_version": 1`), []byte(`"format_version": 99`), 1)
+		}
+		return data
+	})
+	if _, err := backup.Open(bytes.NewReader(future), t.TempDir()); !errors.Is(err, backup.ErrUnsupportedVersion) {
+		t.Errorf("Open future version: expected ErrUnsupportedVersion, got: %v", err)
+	}
+
+	if _, err := backup.Open(bytes.NewReader([]byte("not an archive")), t.TempDir()); !errors.Is(err, backup.ErrInvalidArchive) {
+		t.Errorf("Open garbage: expected ErrInvalidArchive, got: %v", err)
+	}
+}
+
+func TestRestoreBucket(t *testing.T) {
+	src, key := testDB(t)
+	dst := openDB(t, filepath.Join(t.TempDir(), "dst.db"))
+	s := storage.NewEntityStorage(dst, key)
+	if err := s.Add("prod", "other", "keep?"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backup.RestoreBuckets(dst, src, []string{"prod"}, false); !errors.Is(err, backup.ErrExists) {
+		t.Fatalf("RestoreBuckets existing: expected ErrExists, got: %v", err)
+	}
+	if err := backup.RestoreBuckets(dst, src, []string{"prod"}, true); err != nil {
+		t.Fatalf("RestoreBuckets: %v", err)
+	}
+	if _, err := s.Get("prod", "other"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("replaced bucket keeps old key: %v", err)
+	}
+	if v, err := s.GetVersion("prod", "token", 1); err != nil || v != "v1" {
+		t.Errorf("GetVersion = %q, %v", v, err)
+	}
+	if exist, _ := s.BucketExist("stage"); exist {
+		t.Error("only the requested bucket is restored")
+	}
+
+	// a missing bucket fails the whole restore
+	if err := backup.RestoreBuckets(dst, src, []string{"stage", "missing"}, false); !errors.Is(err, storage.ErrBucketNotFound) {
+		t.Errorf("RestoreBuckets missing: expected ErrBucketNotFound, got: %v", err)
+	}
+	if exist, _ := s.BucketExist("stage"); exist {
+		t.Error("failed restore is not rolled back")
+	}
+}
+
+func TestKeyStoreScryptBounds(t *testing.T) {
+	db, _ := testDB(t)
+	var buf bytes.Buffer
+	if _, err := backup.Write(&buf, db, backup.Options{KeyStore: []byte("keys"), Passphrase: []byte("pass")}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// a crafted archive asks for 2^30 iterations, the manifest checksums match the crafted key store
+	var sealed []byte
+	crafted := rewrite(t, buf.Bytes(), func(name string, data []byte) []byte {
+		if name == backup.KeyStoreFile {
+			sealed = bytes.Replace(data, []byte(`"n":32768`), []byte(`"n":1073741824`), 1)
+			return sealed
+		}
+		return data
+	})
+	crafted = rewrite(t, crafted, func(name string, data []byte) []byte {
+		if name != backup.ManifestFile {
+			return data
+		}
+		var m backup.Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		for i, f := range m.Files {
+			if f.Name == backup.KeyStoreFile {
+				sum := sha256.Sum256(sealed)
+				m.Files[i].Size, m.Files[i].SHA256 = int64(len(sealed)), hex.EncodeToString(sum[:])
+			}
+		}
+		data, _ = json.Marshal(m)
+		return data
+	})
+
+	a, err := backup.Open(bytes.NewReader(crafted), t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := a.KeyStore([]byte("pass")); !errors.Is(err, backup.ErrInvalidArchive) || !errors.Is(err, encrypt.ErrorInvalidScryptParams) {
+		t.Errorf("KeyStore: expected ErrInvalidArchive and ErrorInvalidScryptParams, got: %v", err)
+	}
+}
+
+func TestRestoreAll(t *testing.T) {
+	src, key := testDB(t)
+	path := filepath.Join(t.TempDir(), "dst.db")
+	dst := openDB(t, path)
+	s := storage.NewEntityStorage(dst, key)
+	if err := s.Add("old", "token", "gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("prod", "other", "gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backup.RestoreAll(dst, src); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if buckets, _ := s.ListBuckets(); !slices.Equal(buckets, []string{"prod", "stage"}) {
+		t.Errorf("ListBuckets = %v, want [prod stage]", buckets)
+	}
+	if _, err := s.Get("prod", "other"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("restored bucket keeps old key: %v", err)
+	}
+	if v, err := s.GetVersion("prod", "token", 1); err != nil || v != "v1" {
+		t.Errorf("GetVersion = %q, %v", v, err)
+	}
+	if v, err := s.Get("stage", "token"); err != nil || v != "stage" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+}
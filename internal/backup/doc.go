@@ -0,0 +1,18 @@
+/*
+Package backup writes and reads kv backup archives.
+
+An archive is a gzip compressed tar file with:
+
+	kv.db            consistent snapshot of the database (bbolt Tx.WriteTo)
+	keystore.sealed  optional, the encryption key store file sealed with a backup passphrase
+	manifest.json    format version, creation time, bucket names and SHA-256 checksums of the files
+
+Values in the database snapshot stay encrypted with the encryption keys, so an archive
+without the key store can't be read without the keys. The key store file is stored
+as is (including its own passphrase protection) and additionally sealed with
+AES-256-GCM under a key derived from the backup passphrase with scrypt.
+
+Open extracts an archive and verifies the manifest version and every checksum
+before anything can be restored from it.
+*/
+package backup
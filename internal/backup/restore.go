@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+	bboltErr "go.etcd.io/bbolt/errors"
+)
+
+// ErrExists is returned when a restore would replace existing data without being allowed to.
+var ErrExists = errors.New("already exists")
+
+// RestoreBuckets copies buckets with their nested buckets (key history) from src to dst
+// in a single transaction, either all buckets are restored or none is.
+// An existing bucket in dst is replaced only if replace is set.
+func RestoreBuckets(dst, src *bbolt.DB, buckets []string, replace bool) error {
+	return src.View(func(stx *bbolt.Tx) error {
+		return dst.Update(func(dtx *bbolt.Tx) error {
+			for _, name := range buckets {
+				sb := stx.Bucket([]byte(name))
+				if sb == nil {
+					return fmt.Errorf("bucket %q: %w", name, bboltErr.ErrBucketNotFound)
+				}
+				if dtx.Bucket([]byte(name)) != nil {
+					if !replace {
+						return fmt.Errorf("bucket %q: %w", name, ErrExists)
+					}
+					if err := dtx.DeleteBucket([]byte(name)); err != nil {
+						return err
+					}
+				}
+				b, err := dtx.CreateBucket([]byte(name))
+				if err != nil {
+					return err
+				}
+				if err := copyBucket(b, sb); err != nil {
+					return fmt.Errorf("bucket %q: %w", name, err)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// RestoreAll replaces all buckets of dst with the buckets of src in a single transaction.
+// The content of the dst file is replaced in place, so a process waiting for the dst lock
+// opens the restored data afterwards instead of a replaced file.
+func RestoreAll(dst, src *bbolt.DB) error {
+	return src.View(func(stx *bbolt.Tx) error {
+		return dst.Update(func(dtx *bbolt.Tx) error {
+			var names [][]byte
+			err := dtx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+				names = append(names, bytes.Clone(name))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if err := dtx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+			return stx.ForEach(func(name []byte, sb *bbolt.Bucket) error {
+				b, err := dtx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				if err := copyBucket(b, sb); err != nil {
+					return fmt.Errorf("bucket %q: %w", name, err)
+				}
+				return nil
+			})
+		})
+	})
+}
+
+// copyBucket copies all keys, nested buckets and the sequence of src to dst.
+func copyBucket(dst, src *bbolt.Bucket) error {
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, src.Bucket(k))
+	})
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the name of a project configuration file.
+const ProjectFile = ".kv.yaml"
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrProjectPath     = errors.New("project file paths must be relative and stay inside the project directory")
+)
+
+// Profile is a named set of settings.
+type Profile struct {
+	DB                 string `yaml:"db"`
+	EncryptionKeyStore string `yaml:"encryption-key-store"`
+	PassphraseFile     string `yaml:"passphrase-file"`
+	Bucket             string `yaml:"bucket"`
+	Output             string `yaml:"output"`
+	LockTimeout        string `yaml:"lock-timeout"`
+	// Import holds flag values of importers by importer name and flag name.
+	// A value is a string or a list of strings for repeatable flags.
+	Import map[string]map[string]Values `yaml:"import"`
+}
+
+// Values is a setting value, a YAML scalar or a list of scalars.
+type Values []string
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars.
+func (v *Values) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*v = Values{n.Value}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*v = list
+	return nil
+}
+
+// file is the configuration file format.
+type file struct {
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Config is merged configuration from all files.
+type Config struct {
+	// Default is the profile used when none is selected.
+	Default  string
+	Profiles map[string]Profile
+	// Files are the files the configuration was read from.
+	Files []string
+
+	// projectProfiles are the names of profiles set in a project file.
+	projectProfiles map[string]bool
+}
+
+// UserPath returns the path of the user configuration file.
+func UserPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "kv", "config.yaml")
+}
+
+// FindProject returns the path of the nearest project file in dir or its parents,
+// or an empty string if there is none.
+func FindProject(dir string) string {
+	for {
+		p := filepath.Join(dir, ProjectFile)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads configuration files in order, settings of later files override earlier ones.
+// Missing files and empty paths are skipped.
+func Load(paths ...string) (*Config, error) {
+	c := &Config{Profiles: make(map[string]Profile)}
+	for _, path := range paths {
+		if err := c.load(path, false); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadProject reads a project file, its settings override the settings read before.
+// A project file can be found in any parent directory of the working directory,
+// so it is trusted less than the user files: its profile setting doesn't select
+// the default profile and its paths must be relative and stay inside its directory.
+// A missing file and an empty path are skipped.
+func (c *Config) LoadProject(path string) error {
+	return c.load(path, true)
+}
+
+// FromProject reports whether the profile by name, or the default profile for an empty name,
+// has settings from a project file.
+func (c *Config) FromProject(name string) bool {
+	if name == "" {
+		name = c.Default
+	}
+	return c.projectProfiles[name]
+}
+
+// load reads a configuration file and merges its profiles.
+func (c *Config) load(path string, project bool) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for name, p := range f.Profiles {
+		if project {
+			for _, v := range [][2]string{{"db", p.DB}, {"encryption-key-store", p.EncryptionKeyStore}, {"passphrase-file", p.PassphraseFile}} {
+				if !projectPath(v[1]) {
+					return fmt.Errorf("%s: profile %s: %s: %w: %s", path, name, v[0], ErrProjectPath, v[1])
+				}
+			}
+			if c.projectProfiles == nil {
+				c.projectProfiles = make(map[string]bool)
+			}
+			c.projectProfiles[name] = true
+		}
+		p.DB = resolvePath(dir, p.DB)
+		p.EncryptionKeyStore = resolvePath(dir, p.EncryptionKeyStore)
+		p.PassphraseFile = resolvePath(dir, p.PassphraseFile)
+		if p.LockTimeout != "" {
+			if _, err := time.ParseDuration(p.LockTimeout); err != nil {
+				return fmt.Errorf("%s: profile %s: lock-timeout: %w", path, name, err)
+			}
+		}
+		c.Profiles[name] = c.Profiles[name].merge(p)
+	}
+	if f.Profile != "" && !project {
+		c.Default = f.Profile
+	}
+	c.Files = append(c.Files, path)
+	return nil
+}
+
+// Profile returns the profile by name, or the default profile for an empty name.
+// Without a name and a default profile, an empty profile is returned.
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok && len(c.Profiles) == 0 {
+		return Profile{}, fmt.Errorf("%w: %s (no profiles are defined)", ErrProfileNotFound, name)
+	}
+	if !ok {
+		return Profile{}, fmt.Errorf("%w: %s (available: %s)", ErrProfileNotFound, name, strings.Join(c.Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names returns the sorted profile names.
+func (c *Config) Names() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
+
+// merge returns p with the settings set in o.
+func (p Profile) merge(o Profile) Profile {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&p.DB, o.DB)
+	set(&p.EncryptionKeyStore, o.EncryptionKeyStore)
+	set(&p.PassphraseFile, o.PassphraseFile)
+	set(&p.Bucket, o.Bucket)
+	set(&p.Output, o.Output)
+	set(&p.LockTimeout, o.LockTimeout)
+
+	if len(o.Import) > 0 {
+		imp := make(map[string]map[string]Values, len(p.Import)+len(o.Import))
+		for name, flags := range p.Import {
+			imp[name] = maps.Clone(flags)
+		}
+		for name, flags := range o.Import {
+			if imp[name] == nil {
+				imp[name] = make(map[string]Values, len(flags))
+			}
+			maps.Copy(imp[name], flags)
+		}
+		p.Import = imp
+	}
+	return p
+}
+
+// projectPath reports whether a path of a project file is empty, or relative and inside the file directory.
+func projectPath(path string) bool {
+	if path == "" {
+		return true
+	}
+	return !strings.HasPrefix(path, "~") && filepath.IsLocal(filepath.FromSlash(path))
+}
+
+// resolvePath expands ~ and makes a relative path relative to dir.
+func resolvePath(dir, path string) string {
+	switch {
+	case path == "":
+		return ""
+	case path == "~" || strings.HasPrefix(path, "~/"):
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+		return path
+	case filepath.IsAbs(path):
+		return path
+	}
+	return filepath.Join(dir, path)
+}
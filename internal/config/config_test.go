@@ -0,0 +1,205 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/config"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMerge(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "home", "config.yaml")
+	project := filepath.Join(dir, "project", config.ProjectFile)
+
+	writeFile(t, user, `
+profile: personal
+profiles:
+  personal:
+    db: /data/personal.db
+  work:
+    db: /data/work.db
+    encryption-key-store: work.key
+    bucket: dev
+    import:
+      ssm:
+        aws-profile: work
+        aws-region: eu-west-1
+`)
+	writeFile(t, project, `
+profile: work
+profiles:
+  work:
+    bucket: prod
+    output: json
+    lock-timeout: 30s
+    import:
+      ssm:
+        aws-region: us-east-1
+      secretsmanager:
+        tag: [team=payments, env=prod]
+`)
+
+	cfg, err := config.Load(user, filepath.Join(dir, "missing.yaml"), "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.LoadProject(project); err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	if want := []string{user, project}; !reflect.DeepEqual(cfg.Files, want) {
+		t.Errorf("Files = %v, want %v", cfg.Files, want)
+	}
+	if want := []string{"personal", "work"}; !reflect.DeepEqual(cfg.Names(), want) {
+		t.Errorf("Names = %v, want %v", cfg.Names(), want)
+	}
+
+	// the project file doesn't select the default profile
+	p, err := cfg.Profile("")
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if p.DB != "/data/personal.db" || cfg.FromProject("") {
+		t.Errorf("Profile() = %+v, want the personal profile of the user file", p)
+	}
+
+	p, err = cfg.Profile("work")
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if !cfg.FromProject("work") {
+		t.Error("FromProject(work) = false, want true")
+	}
+	want := config.Profile{
+		DB:                 "/data/work.db",
+		EncryptionKeyStore: filepath.Join(dir, "home", "work.key"),
+		Bucket:             "prod",
+		Output:             "json",
+		LockTimeout:        "30s",
+		Import: map[string]map[string]config.Values{
+			"ssm":            {"aws-profile": {"work"}, "aws-region": {"us-east-1"}},
+			"secretsmanager": {"tag": {"team=payments", "env=prod"}},
+		},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Profile = %+v, want %+v", p, want)
+	}
+
+	p, err = cfg.Profile("personal")
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if p.DB != "/data/personal.db" || p.Bucket != "" {
+		t.Errorf("Profile(personal) = %+v", p)
+	}
+
+	if _, err := cfg.Profile("missing"); !errors.Is(err, config.ErrProfileNotFound) {
+		t.Errorf("Profile(missing) error = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestLoadProjectPaths(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, config.ProjectFile)
+
+	writeFile(t, project, "profiles:\n  work:\n    db: data/kv.db\n    passphrase-file: ./kv.passphrase\n")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.LoadProject(project); err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	p, _ := cfg.Profile("work")
+	if p.DB != filepath.Join(dir, "data", "kv.db") || p.PassphraseFile != filepath.Join(dir, "kv.passphrase") {
+		t.Errorf("Profile(work) = %+v", p)
+	}
+
+	for _, path := range []string{"/etc/kv.db", "~/kv.db", "../kv.db", "data/../../kv.db"} {
+		writeFile(t, project, "profiles:\n  work:\n    db: "+path+"\n")
+		cfg, _ := config.Load()
+		if err := cfg.LoadProject(project); !errors.Is(err, config.ErrProjectPath) {
+			t.Errorf("LoadProject(db: %s) error = %v, want ErrProjectPath", path, err)
+		}
+	}
+
+	// the same paths are allowed in the user file
+	writeFile(t, project, "profiles:\n  work:\n    db: ../kv.db\n")
+	if _, err := config.Load(project); err != nil {
+		t.Errorf("Load: %v", err)
+	}
+}
+
+func TestProfileWithoutConfig(t *testing.T) {
+	cfg, err := config.Load(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	p, err := cfg.Profile("")
+	if err != nil || !reflect.DeepEqual(p, config.Profile{}) {
+		t.Errorf("Profile = %+v, %v, want empty profile", p, err)
+	}
+	if _, err := cfg.Profile("work"); !errors.Is(err, config.ErrProfileNotFound) {
+		t.Errorf("Profile(work) error = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "profiles: [")
+	if _, err := config.Load(path); err == nil {
+		t.Fatal("Load: expected error")
+	}
+}
+
+func TestLoadInvalidLockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "profiles:\n  work:\n    lock-timeout: soon\n")
+	if _, err := config.Load(path); err == nil {
+		t.Fatal("Load: expected error")
+	}
+}
+
+func TestFindProject(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "a", config.ProjectFile)
+	writeFile(t, project, "profiles: {}")
+	nested := filepath.Join(dir, "a", "b", "c")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := config.FindProject(nested); got != project {
+		t.Errorf("FindProject(nested) = %q, want %q", got, project)
+	}
+	if got := config.FindProject(filepath.Join(dir, "a")); got != project {
+		t.Errorf("FindProject(a) = %q, want %q", got, project)
+	}
+	// a directory with the project file name is not a project file
+	if err := os.Mkdir(filepath.Join(nested, config.ProjectFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := config.FindProject(nested); got != project {
+		t.Errorf("FindProject(nested with directory) = %q, want %q", got, project)
+	}
+}
+
+func TestUserPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got, want := config.UserPath(), filepath.Join("/xdg", "kv", "config.yaml"); got != want {
+		t.Errorf("UserPath = %q, want %q", got, want)
+	}
+}
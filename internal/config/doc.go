@@ -0,0 +1,34 @@
+/*
+Configuration profiles
+
+Profiles are read from the user configuration file (~/.config/kv/config.yaml,
+or $XDG_CONFIG_HOME/kv/config.yaml) and from a project file (.kv.yaml) found
+in the working directory or one of its parents. The project file is read last,
+its settings override the user file settings of a profile with the same name.
+
+	profile: work # used when --profile and KV_PROFILE are not set
+	profiles:
+	  work:
+	    db: ~/work/.kv.db
+	    encryption-key-store: ~/work/.kv.key
+	    bucket: prod
+	    output: json
+	    lock-timeout: 30s
+	    import:
+	      ssm:
+	        aws-profile: work
+	        aws-region: eu-west-1
+	      secretsmanager:
+	        tag: [team=payments, env=prod]
+	  personal:
+	    db: ~/.kv.db
+
+Relative paths are resolved against the directory of the file they are set in.
+
+A project file is trusted less, as it can be found in any parent directory: its
+profile setting is ignored, only the user file selects the default profile, and its
+db, encryption-key-store and passphrase-file paths must be relative and stay inside
+its directory (ErrProjectPath).
+Importer settings are flag values of "kv import <name>", flags set on the command line win.
+*/
+package config
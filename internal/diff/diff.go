@@ -0,0 +1,50 @@
+// Package diff compares two sets of key values.
+package diff
+
+import (
+	"maps"
+	"slices"
+)
+
+// Kind is the kind of a key difference.
+type Kind string
+
+const (
+	Added   Kind = "added"   // only in the target
+	Removed Kind = "removed" // only in the source
+	Changed Kind = "changed" // in both with different values
+)
+
+// Change is a difference of a key between the source and the target.
+type Change struct {
+	Key  string `json:"key"`
+	Kind Kind   `json:"kind"`
+	Old  string `json:"old,omitempty"` // source value, empty for Added
+	New  string `json:"new,omitempty"` // target value, empty for Removed
+}
+
+// Compare returns the changes that turn from into to, sorted by key.
+func Compare(from map[string]string, to map[string]string) []Change {
+	keys := slices.Collect(maps.Keys(from))
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	var changes []Change
+	for _, k := range keys {
+		ov, inFrom := from[k]
+		nv, inTo := to[k]
+		switch {
+		case !inFrom:
+			changes = append(changes, Change{Key: k, Kind: Added, New: nv})
+		case !inTo:
+			changes = append(changes, Change{Key: k, Kind: Removed, Old: ov})
+		case ov != nv:
+			changes = append(changes, Change{Key: k, Kind: Changed, Old: ov, New: nv})
+		}
+	}
+	return changes
+}
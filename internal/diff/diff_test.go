@@ -0,0 +1,30 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/diff"
+)
+
+func TestCompare(t *testing.T) {
+	from := map[string]string{"same": "1", "changed": "old", "removed": "x"}
+	to := map[string]string{"same": "1", "changed": "new", "added": "y"}
+
+	got := diff.Compare(from, to)
+	want := []diff.Change{
+		{Key: "added", Kind: diff.Added, New: "y"},
+		{Key: "changed", Kind: diff.Changed, Old: "old", New: "new"},
+		{Key: "removed", Kind: diff.Removed, Old: "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() = %+v; want %+v", got, want)
+	}
+
+	if got := diff.Compare(from, from); len(got) != 0 {
+		t.Errorf("Compare() of equal maps = %+v; want no changes", got)
+	}
+	if got := diff.Compare(nil, map[string]string{"a": ""}); len(got) != 1 || got[0].Kind != diff.Added {
+		t.Errorf("Compare() with empty value = %+v", got)
+	}
+}
@@ -0,0 +1,20 @@
+/*
+Package doctor checks the integrity of a kv database and its encryption key store.
+
+Run reports problems as findings instead of stopping at the first one:
+
+	database         bbolt consistency check (Tx.Check) failed
+	bucket-key       bucket has no key store entry and uses the default key, or its key is invalid
+	orphan-key       key store entry has no matching bucket
+	value-prefix     value was encrypted with a key of another size than the bucket key
+	decrypt          value can't be decrypted with the bucket key
+	network-storage  file is on a network filesystem, locking and fsync may not be reliable
+	permissions      file is readable or writable by group or others
+
+Values are checked with their current version and every version in the key history.
+They are not checked when the database consistency check fails.
+
+Findings are errors or warnings. A bucket without a key store entry, an unused
+key store entry and network storage are warnings, everything else is an error.
+*/
+package doctor
@@ -0,0 +1,311 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/utils"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+// Severity of a finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Check names.
+const (
+	CheckDatabase       = "database"
+	CheckBucketKey      = "bucket-key"
+	CheckOrphanKey      = "orphan-key"
+	CheckValuePrefix    = "value-prefix"
+	CheckDecrypt        = "decrypt"
+	CheckNetworkStorage = "network-storage"
+	CheckPermissions    = "permissions"
+)
+
+// ErrFailed is returned by callers when a report has findings that fail the check.
+var ErrFailed = errors.New("integrity check failed")
+
+// defaultKey is the key store entry used for buckets without their own key.
+const defaultKey = "default"
+
+// Finding is a problem found by a check.
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Path     string   `json:"path,omitempty"`
+	Bucket   string   `json:"bucket,omitempty"`
+	Key      string   `json:"key,omitempty"`
+	Version  uint64   `json:"version,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// String returns the finding as a single line.
+func (f Finding) String() string {
+	var where []string
+	if f.Path != "" {
+		where = append(where, f.Path)
+	}
+	if f.Bucket != "" {
+		where = append(where, "bucket "+f.Bucket)
+	}
+	if f.Key != "" {
+		k := "key " + f.Key
+		if f.Version != 0 {
+			k += fmt.Sprintf(" version %d", f.Version)
+		}
+		where = append(where, k)
+	}
+	if len(where) == 0 {
+		return fmt.Sprintf("%s [%s] %s", f.Severity, f.Check, f.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Check, strings.Join(where, ", "), f.Message)
+}
+
+// Options of the checks.
+type Options struct {
+	// DBPath is the database file path, used for the file checks.
+	DBPath string
+	// KeyStorePath is the encryption key store file path, empty when the key store is not used.
+	KeyStorePath string
+	// Keys are the encryption keys by bucket name, the "default" key is used for buckets without one.
+	// Values are not checked when Keys is empty.
+	Keys map[string]string
+}
+
+// Report is the result of the checks.
+type Report struct {
+	Buckets  int       `json:"buckets"`
+	Values   int       `json:"values"`
+	Findings []Finding `json:"findings"`
+}
+
+// Errors returns the number of findings with the error severity.
+func (r Report) Errors() int {
+	return r.count(SeverityError)
+}
+
+// Warnings returns the number of findings with the warning severity.
+func (r Report) Warnings() int {
+	return r.count(SeverityWarning)
+}
+
+func (r Report) count(s Severity) int {
+	var n int
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// Run checks the database and the files of the store.
+// It returns an error only when the checks can't be run.
+func Run(db *bbolt.DB, opts Options) (Report, error) {
+	r := Report{Findings: []Finding{}}
+
+	for _, path := range []string{opts.DBPath, opts.KeyStorePath} {
+		checkFile(&r, path)
+	}
+
+	consistent, err := checkDatabase(&r, db)
+	if err != nil {
+		return r, err
+	}
+
+	buckets, err := storage.NewEntityStorage(db, "").ListBuckets()
+	if err != nil {
+		return r, fmt.Errorf("list buckets: %w", err)
+	}
+	r.Buckets = len(buckets)
+
+	if opts.KeyStorePath != "" {
+		checkKeyStore(&r, buckets, opts.Keys)
+	}
+	if !consistent || len(opts.Keys) == 0 {
+		return r, nil
+	}
+	for _, b := range buckets {
+		if err := checkValues(&r, db, b, bucketKey(opts.Keys, b)); err != nil {
+			return r, fmt.Errorf("bucket '%s': %w", b, err)
+		}
+	}
+	return r, nil
+}
+
+// checkFile reports loose permissions and network storage of a file, a missing file is skipped.
+func checkFile(r *Report, path string) {
+	if path == "" {
+		return
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	// file modes don't describe access control on Windows
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+		r.add(Finding{
+			Check:    CheckPermissions,
+			Severity: SeverityError,
+			Path:     path,
+			Message:  fmt.Sprintf("permissions %04o are looser than 0600, run: chmod 600 %s", perm, path),
+		})
+	}
+	if onNet, err := utils.IsOnNetworkStorage(path); err == nil && onNet {
+		r.add(Finding{
+			Check:    CheckNetworkStorage,
+			Severity: SeverityWarning,
+			Path:     path,
+			Message:  "file is on a network filesystem, file locking and fsync may not be reliable",
+		})
+	}
+}
+
+// checkDatabase runs the bbolt consistency check and reports whether the database is consistent.
+func checkDatabase(r *Report, db *bbolt.DB) (bool, error) {
+	consistent := true
+	err := db.View(func(tx *bbolt.Tx) error {
+		for err := range tx.Check() {
+			consistent = false
+			r.add(Finding{Check: CheckDatabase, Severity: SeverityError, Message: err.Error()})
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("check database: %w", err)
+	}
+	return consistent, nil
+}
+
+// checkKeyStore reports buckets without a key store entry and key store entries without a bucket.
+func checkKeyStore(r *Report, buckets []string, keys map[string]string) {
+	for _, b := range buckets {
+		if b == defaultKey {
+			continue
+		}
+		if _, ok := keys[b]; !ok {
+			r.add(Finding{
+				Check:    CheckBucketKey,
+				Severity: SeverityWarning,
+				Bucket:   b,
+				Message:  "bucket has no key in the key store and uses the default key",
+			})
+		}
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if name != defaultKey && !slices.Contains(buckets, name) {
+			r.add(Finding{
+				Check:    CheckOrphanKey,
+				Severity: SeverityWarning,
+				Bucket:   name,
+				Message:  "key store has a key for a bucket that doesn't exist",
+			})
+		}
+	}
+}
+
+// checkValues decrypts every value in a bucket with the bucket key.
+func checkValues(r *Report, db *bbolt.DB, bucket, key string) error {
+	prefix, err := keyPrefix(key)
+	if err != nil {
+		r.add(Finding{Check: CheckBucketKey, Severity: SeverityError, Bucket: bucket, Message: err.Error()})
+		return nil
+	}
+
+	s := storage.NewEntityStorage(db, key)
+	migrated, err := s.Migrated()
+	if err != nil {
+		return err
+	}
+	c := storage.KeyCipher(key)
+	return s.Scan(bucket, func(k string, version uint64, encValue string) error {
+		r.Values++
+		if migrated && !encrypt.IsV2(encValue) {
+			r.add(Finding{
+				Check:    CheckDecrypt,
+				Severity: SeverityError,
+				Bucket:   bucket,
+				Key:      k,
+				Version:  version,
+				Message:  storage.ErrLegacyValue.Error(),
+			})
+			return nil
+		}
+		if p := valuePrefix(encValue); p != "" && p != prefix {
+			r.add(Finding{
+				Check:    CheckValuePrefix,
+				Severity: SeverityError,
+				Bucket:   bucket,
+				Key:      k,
+				Version:  version,
+				Message: fmt.Sprintf("value is encrypted with an %s key, the bucket key is %s",
+					strings.TrimSuffix(p, ":"), strings.TrimSuffix(prefix, ":")),
+			})
+			return nil
+		}
+		if _, err := c.Open(bucket, k, encValue); err != nil {
+			r.add(Finding{
+				Check:    CheckDecrypt,
+				Severity: SeverityError,
+				Bucket:   bucket,
+				Key:      k,
+				Version:  version,
+				Message:  err.Error(),
+			})
+		}
+		return nil
+	})
+}
+
+// bucketKey returns the key of a bucket, or the default key.
+func bucketKey(keys map[string]string, bucket string) string {
+	if k, ok := keys[bucket]; ok && k != "" {
+		return k
+	}
+	return keys[defaultKey]
+}
+
+// keyPrefix returns the value prefix for the size of an encryption key.
+func keyPrefix(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("no encryption key for the bucket and no default key")
+	}
+	raw, err := encrypt.DecodeAESKey(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return encrypt.AESKeySize(len(raw)).Prefix()
+}
+
+// valuePrefix returns the key size prefix of an encrypted value, or "" for values
+// written without a prefix.
+func valuePrefix(encValue string) string {
+	encValue = strings.TrimPrefix(encValue, encrypt.PrefixV2)
+	for _, p := range []string{encrypt.PrefixAES128, encrypt.PrefixAES192, encrypt.PrefixAES256} {
+		if strings.HasPrefix(encValue, p) {
+			return p
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package doctor_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/doctor"
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+func openDB(t *testing.T) (*bbolt.DB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kv.db")
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, path
+}
+
+func genKey(t *testing.T, size encrypt.AESKeySize) string {
+	t.Helper()
+	k, err := encrypt.GenerateRandomAESKey(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func add(t *testing.T, db *bbolt.DB, key, bucket, name, value string) {
+	t.Helper()
+	if err := storage.NewEntityStorage(db, key).Add(bucket, name, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// findings returns the findings of a check.
+func findings(r doctor.Report, check string) []doctor.Finding {
+	var fs []doctor.Finding
+	for _, f := range r.Findings {
+		if f.Check == check {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+func TestRunHealthy(t *testing.T) {
+	db, path := openDB(t)
+	def, prod := genKey(t, encrypt.AES256), genKey(t, encrypt.AES256)
+	add(t, db, def, "default", "a", "1")
+	add(t, db, prod, "prod", "token", "v1")
+	add(t, db, prod, "prod", "token", "v2")
+
+	r, err := doctor.Run(db, doctor.Options{DBPath: path, Keys: map[string]string{"default": def, "prod": prod}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(r.Findings) != 0 {
+		t.Errorf("Expected no findings, got: %v", r.Findings)
+	}
+	// 2 current values + 3 history versions
+	if r.Buckets != 2 || r.Values != 5 {
+		t.Errorf("Expected 2 buckets and 5 values, got %d and %d", r.Buckets, r.Values)
+	}
+}
+
+func TestRunKeyStoreEntries(t *testing.T) {
+	db, path := openDB(t)
+	def := genKey(t, encrypt.AES256)
+	add(t, db, def, "stage", "a", "1")
+
+	keys := map[string]string{"default": def, "old": genKey(t, encrypt.AES256)}
+	r, err := doctor.Run(db, doctor.Options{DBPath: path, KeyStorePath: filepath.Join(t.TempDir(), "missing.key"), Keys: keys})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if fs := findings(r, doctor.CheckBucketKey); len(fs) != 1 || fs[0].Bucket != "stage" || fs[0].Severity != doctor.SeverityWarning {
+		t.Errorf("Expected a bucket-key warning for stage, got: %v", fs)
+	}
+	if fs := findings(r, doctor.CheckOrphanKey); len(fs) != 1 || fs[0].Bucket != "old" {
+		t.Errorf("Expected an orphan-key finding for old, got: %v", fs)
+	}
+	if r.Errors() != 0 || r.Warnings() != 2 {
+		t.Errorf("Expected 0 errors and 2 warnings, got %d and %d", r.Errors(), r.Warnings())
+	}
+
+	// without a key store the entries are not checked
+	r, err = doctor.Run(db, doctor.Options{DBPath: path, Keys: keys})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(r.Findings) != 0 {
+		t.Errorf("Expected no findings without a key store, got: %v", r.Findings)
+	}
+}
+
+func TestRunValues(t *testing.T) {
+	db, path := openDB(t)
+	key := genKey(t, encrypt.AES256)
+	add(t, db, key, "prod", "ok", "1")
+	add(t, db, genKey(t, encrypt.AES128), "prod", "short", "2")
+	add(t, db, genKey(t, encrypt.AES256), "prod", "other", "3")
+
+	r, err := doctor.Run(db, doctor.Options{DBPath: path, Keys: map[string]string{"default": key}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// current value and its history version
+	if fs := findings(r, doctor.CheckValuePrefix); len(fs) != 2 || fs[0].Key != "short" {
+		t.Errorf("Expected value-prefix findings for short, got: %v", fs)
+	}
+	if fs := findings(r, doctor.CheckDecrypt); len(fs) != 2 || fs[0].Key != "other" {
+		t.Errorf("Expected decrypt findings for other, got: %v", fs)
+	}
+	if r.Errors() != 4 {
+		t.Errorf("Expected 4 errors, got %d: %v", r.Errors(), r.Findings)
+	}
+}
+
+func TestRunInvalidKey(t *testing.T) {
+	db, path := openDB(t)
+	add(t, db, genKey(t, encrypt.AES256), "prod", "a", "1")
+
+	r, err := doctor.Run(db, doctor.Options{DBPath: path, Keys: map[string]string{"default": "b64:AAAA"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if fs := findings(r, doctor.CheckBucketKey); len(fs) != 1 || fs[0].Severity != doctor.SeverityError {
+		t.Errorf("Expected a bucket-key error, got: %v", r.Findings)
+	}
+	if r.Values != 0 {
+		t.Errorf("Expected values not to be checked with an invalid key, got %d", r.Values)
+	}
+}
+
+func TestRunPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on Windows")
+	}
+	db, path := openDB(t)
+	ks := filepath.Join(t.TempDir(), "kv.key")
+	if err := os.WriteFile(ks, []byte("keys: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(ks, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := doctor.Run(db, doctor.Options{DBPath: path, KeyStorePath: ks})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if fs := findings(r, doctor.CheckPermissions); len(fs) != 1 || fs[0].Path != ks || fs[0].Severity != doctor.SeverityError {
+		t.Errorf("Expected a permissions error for the key store, got: %v", r.Findings)
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	f := doctor.Finding{Check: doctor.CheckDecrypt, Severity: doctor.SeverityError, Bucket: "prod", Key: "token", Version: 2, Message: "decryption failed"}
+	if got, want := f.String(), "error [decrypt] bucket prod, key token version 2: decryption failed"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRunLegacyValueAfterMigrate(t *testing.T) {
+	db, path := openDB(t)
+	key := genKey(t, encrypt.AES256)
+	add(t, db, key, "prod", "a", "1")
+	s := storage.NewEntityStorage(db, key)
+	if err := s.MarkMigrated(); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := encrypt.NewAES(key, "old").Encrypt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte("prod")).Put([]byte("swapped"), []byte(legacy))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := doctor.Run(db, doctor.Options{DBPath: path, Keys: map[string]string{"default": key}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if fs := findings(r, doctor.CheckDecrypt); len(fs) != 1 || fs[0].Key != "swapped" {
+		t.Errorf("Expected a decrypt finding for swapped, got: %v", r.Findings)
+	}
+	if r.Buckets != 1 {
+		t.Errorf("Expected 1 bucket, got %d", r.Buckets)
+	}
+}
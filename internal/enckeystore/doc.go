@@ -9,8 +9,10 @@
 //   - AddDefaultKey: set "default" once; does not replace if it already exists
 //   - EnsureDefaultKey: create a fresh AES-256 default if missing
 //   - AddKey: add a new, per-bucket key (no replacement allowed)
+//   - RotateKey / FinishRotation / CancelRotation: replace a bucket key deliberately
 //   - Get: return the key for a bucket or the valid "default" fallback
 //   - HasKey / ListBuckets: inspect what’s present
+//   - SetPassphrase: seal the store on disk with a passphrase
 //
 // All public methods are safe for concurrent use; the store guards internal
 // state with an RWMutex. Disk I/O is explicit—callers decide when to Load and Save.
@@ -32,16 +34,41 @@
 // The YAML file looks like:
 //
 //	keys:
-//	  default: "<base64-or-hex-aes-key>"
-//	  photos:  "<base64-or-hex-aes-key>"
-//	  logs:    "<base64-or-hex-aes-key>"
+//	  default: "b64:<base64-aes-key>"
+//	  photos:  "hex:<hex-aes-key>"
+//	  logs:    "<legacy-printable-aes-key>"
+//	retired:
+//	  photos:
+//	    - "b64:<base64-aes-key>"
+//
+// Keys are decoded by the encrypt package: "b64:" and "hex:" prefixed keys hold
+// full-entropy raw bytes, keys without prefix are legacy printable keys.
+// The "retired" section is present only while a rotation is unfinished.
 //
 // Unknown fields are rejected on Load() (yaml.KnownFields(true)), helping catch
 // typos and format drift.
 //
+// # Passphrase protection
+//
+// With SetPassphrase, Save() seals the store: the YAML above is encrypted with
+// AES-256-GCM (encrypt package) under a key derived from the passphrase with scrypt,
+// and the file holds only the sealed form:
+//
+//	sealed:
+//	  kdf: scrypt
+//	  params: {n: 32768, r: 8, p: 1}
+//	  salt: "<base64>"
+//	  data: "aes256:<base64>"
+//
+// Load() of a sealed file returns ErrPassphraseRequired unless a passphrase is set,
+// and ErrWrongPassphrase if it doesn't match. The scrypt parameters of the file are
+// checked against encrypt.MaxScryptN, MaxScryptR and MaxScryptP before the key is
+// derived, so a crafted file can't make Load() use unbounded memory. A plaintext store is migrated by
+// loading it, setting a passphrase and saving; SetPassphrase(nil) reverts it.
+//
 // # Atomic writes & permissions
 //
-// Save() performs an atomic, durable write sequence:
+// Save() performs an atomic, durable write sequence (utils.AtomicWriteFile):
 //
 //  1. write to a temp file in the same directory
 //  2. flush and fsync the temp file
@@ -53,11 +80,22 @@
 //
 // # Replacement policy
 //
-// AddKey() and AddDefaultKey() do not replace existing entries. Replacements
-// must be done manually by editing the on-disk YAML (or deleting and re-adding).
-// This is intentional to avoid accidental key rotation. EnsureDefaultKey()
+// AddKey() and AddDefaultKey() do not replace existing entries. This is
+// intentional to avoid accidental key rotation. EnsureDefaultKey()
 // never replaces an existing "default"—it only creates one if missing.
 //
+// Rotation is explicit and done in steps, so existing ciphertexts never
+// become undecryptable:
+//
+//  1. RotateKey sets the new key and keeps the key the bucket used so far
+//     (its own or "default") as retired; the caller saves the store
+//  2. the caller re-encrypts the bucket values with the new key
+//  3. FinishRotation drops the retired keys (or CancelRotation restores
+//     the previous key if re-encryption failed); the caller saves the store
+//
+// If the process dies between the steps, the retired keys are still on disk
+// and can be used to finish the rotation later.
+//
 // # Example
 //
 // The following example shows a typical lifecycle: load (or initialize),
@@ -126,6 +164,6 @@
 //   - Keep the YAML file on a trusted filesystem; Save() enforces 0600 but your
 //     environment and backups still matter.
 //   - Consider process memory exposure if logging keys; avoid printing key values.
-//   - If you need rotation, design a deliberate process to edit the YAML and
-//     roll keys carefully across dependent systems.
+//   - Rotate keys with RotateKey rather than editing the YAML, otherwise
+//     existing ciphertexts can't be decrypted anymore.
 package enckeystore
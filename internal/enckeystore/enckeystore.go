@@ -1,16 +1,18 @@
 package enckeystore
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 
+	"github.com/yousysadmin/kv/internal/utils"
 	"github.com/yousysadmin/kv/pkg/encrypt"
 	"gopkg.in/yaml.v3"
 )
@@ -37,10 +39,23 @@ func GenerateEncryptionKey() (EncryptionKey, error) {
 type EncryptionKeyStore struct {
 	path string                   `yaml:"-"`
 	Keys map[string]EncryptionKey `yaml:"keys"`
+	// Retired holds keys replaced by an unfinished rotation, per bucket.
+	Retired map[string][]EncryptionKey `yaml:"retired,omitempty"`
+
+	// passphrase seals the store on disk, nil keeps it in plaintext.
+	passphrase []byte
 
 	mu sync.RWMutex
 }
 
+// onDisk is the YAML layout of the store file.
+// A passphrase protected file holds only the sealed section.
+type onDisk struct {
+	Keys    map[string]EncryptionKey   `yaml:"keys,omitempty"`
+	Retired map[string][]EncryptionKey `yaml:"retired,omitempty"`
+	Sealed  *encrypt.SealedBox         `yaml:"sealed,omitempty"`
+}
+
 // NewEncryptionKeyStore creates an empty store bound to a file path.
 func NewEncryptionKeyStore(path string) *EncryptionKeyStore {
 	return &EncryptionKeyStore{
@@ -65,35 +80,57 @@ func (s *EncryptionKeyStore) Load() error {
 		return err
 	}
 
-	type onDisk struct {
-		Keys map[string]EncryptionKey `yaml:"keys"`
-	}
-	var disk onDisk
-
-	dec := yaml.NewDecoder(strings.NewReader(string(b)))
-	dec.KnownFields(true)
-	if err := dec.Decode(&disk); err != nil {
+	disk, err := decodeOnDisk(b)
+	if err != nil {
 		return fmt.Errorf("parse %s: %w", s.path, err)
 	}
+	if disk.Sealed != nil {
+		if len(s.passphrase) == 0 {
+			return ErrPassphraseRequired
+		}
+		plain, err := open(disk.Sealed, s.passphrase)
+		if err != nil {
+			return err
+		}
+		if disk, err = decodeOnDisk(plain); err != nil {
+			return fmt.Errorf("parse sealed %s: %w", s.path, err)
+		}
+		if disk.Sealed != nil {
+			return fmt.Errorf("parse sealed %s: %w", s.path, errNestedSealed)
+		}
+	}
 
 	if disk.Keys == nil {
 		disk.Keys = make(map[string]EncryptionKey)
 	}
 	s.Keys = disk.Keys
+	s.Retired = disk.Retired
 	return nil
 }
 
+// decodeOnDisk parses the store file, unknown fields are rejected.
+func decodeOnDisk(b []byte) (onDisk, error) {
+	var disk onDisk
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&disk); err != nil {
+		return disk, err
+	}
+	return disk, nil
+}
+
 // ReLoad is an alias for Load.
 func (s *EncryptionKeyStore) ReLoad() error { return s.Load() }
 
 // Save writes to disk atomically with 0600 perms.
+// If a passphrase is set, the store is sealed with it.
 func (s *EncryptionKeyStore) Save() error {
 	s.mu.RLock()
-	dump := struct {
-		Keys map[string]EncryptionKey `yaml:"keys"`
-	}{
-		Keys: s.copyKeysLocked(),
+	dump := onDisk{
+		Keys:    s.copyKeysLocked(),
+		Retired: s.copyRetiredLocked(),
 	}
+	passphrase := s.passphrase
 	s.mu.RUnlock()
 
 	data, err := yaml.Marshal(&dump)
@@ -101,57 +138,22 @@ func (s *EncryptionKeyStore) Save() error {
 		return fmt.Errorf("yaml marshal: %w", err)
 	}
 
+	if len(passphrase) > 0 {
+		box, err := seal(passphrase, data)
+		if err != nil {
+			return err
+		}
+		if data, err = yaml.Marshal(&onDisk{Sealed: box}); err != nil {
+			return fmt.Errorf("yaml marshal: %w", err)
+		}
+	}
+
 	dir := filepath.Dir(s.path)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return fmt.Errorf("mkdir %s: %w", dir, err)
 	}
 
-	return atomicWriteFile(s.path, data, 0o600)
-}
-
-// atomicWriteFile writes to a temp file, fsyncs file & dir, then renames.
-func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
-	dir := filepath.Dir(path)
-	tmp, err := os.CreateTemp(dir, ".tmp-keys-*")
-	if err != nil {
-		return fmt.Errorf("create temp: %w", err)
-	}
-	tmpPath := tmp.Name()
-	defer func() { _ = os.Remove(tmpPath) }()
-
-	if err := tmp.Chmod(perm); err != nil {
-		_ = tmp.Close()
-		return fmt.Errorf("chmod temp: %w", err)
-	}
-
-	w := bufio.NewWriter(tmp)
-	if _, err := w.Write(data); err != nil {
-		_ = tmp.Close()
-		return fmt.Errorf("write temp: %w", err)
-	}
-	if err := w.Flush(); err != nil {
-		_ = tmp.Close()
-		return fmt.Errorf("flush temp: %w", err)
-	}
-	if err := tmp.Sync(); err != nil {
-		_ = tmp.Close()
-		return fmt.Errorf("fsync temp: %w", err)
-	}
-	if err := tmp.Close(); err != nil {
-		return fmt.Errorf("close temp: %w", err)
-	}
-
-	if err := os.Rename(tmpPath, path); err != nil {
-		return fmt.Errorf("rename temp -> %s: %w", path, err)
-	}
-
-	// fsync the directory to persist the rename on some filesystems
-	dirFD, err := os.Open(dir)
-	if err == nil {
-		_ = dirFD.Sync()
-		_ = dirFD.Close()
-	}
-	return nil
+	return utils.AtomicWriteFile(s.path, data, 0o600)
 }
 
 // Get returns the key for bucketName if present and valid,
@@ -238,6 +240,70 @@ func (s *EncryptionKeyStore) EnsureDefaultKey() (EncryptionKey, error) {
 	return s.AddDefaultKey("")
 }
 
+// RotateKey replaces the key of a bucket with newKey after validation.
+// The key the bucket used so far (its own or "default") is kept as retired
+// until FinishRotation is called, so values that are not re-encrypted yet can still be read.
+// It returns the previous own key of the bucket, or "" if the bucket used the "default" key.
+func (s *EncryptionKeyStore) RotateKey(bucketName string, newKey EncryptionKey) (EncryptionKey, error) {
+	if strings.TrimSpace(bucketName) == "" {
+		return "", fmt.Errorf("bucket name cannot be empty")
+	}
+	if err := newKey.Validate(); err != nil {
+		return "", fmt.Errorf("invalid encryption key for bucket %q: %w", bucketName, err)
+	}
+	old, err := s.Get(bucketName)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Keys == nil {
+		s.Keys = make(map[string]EncryptionKey)
+	}
+	if s.Retired == nil {
+		s.Retired = make(map[string][]EncryptionKey)
+	}
+	prev := s.Keys[bucketName]
+	if !slices.Contains(s.Retired[bucketName], old) {
+		s.Retired[bucketName] = append(s.Retired[bucketName], old)
+	}
+	s.Keys[bucketName] = newKey
+	return prev, nil
+}
+
+// FinishRotation drops retired keys of a bucket once all its values are re-encrypted.
+func (s *EncryptionKeyStore) FinishRotation(bucketName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Retired, bucketName)
+}
+
+// CancelRotation restores the previous own key of a bucket returned by RotateKey
+// ("" switches the bucket back to the "default" key) and drops the last retired key.
+func (s *EncryptionKeyStore) CancelRotation(bucketName string, prev EncryptionKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev == "" {
+		delete(s.Keys, bucketName)
+	} else {
+		s.Keys[bucketName] = prev
+	}
+	if r := s.Retired[bucketName]; len(r) > 1 {
+		s.Retired[bucketName] = r[:len(r)-1]
+	} else {
+		delete(s.Retired, bucketName)
+	}
+}
+
+// RetiredKeys returns keys replaced by an unfinished rotation of a bucket.
+func (s *EncryptionKeyStore) RetiredKeys(bucketName string) []EncryptionKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.Retired[bucketName])
+}
+
 // HasKey reports true if the store currently holds any key for the bucket (no validation).
 func (s *EncryptionKeyStore) HasKey(bucketName string) bool {
 	s.mu.RLock()
@@ -264,3 +330,15 @@ func (s *EncryptionKeyStore) copyKeysLocked() map[string]EncryptionKey {
 	maps.Copy(cp, s.Keys)
 	return cp
 }
+
+// copyRetiredLocked clones s.Retired under read lock.
+func (s *EncryptionKeyStore) copyRetiredLocked() map[string][]EncryptionKey {
+	if len(s.Retired) == 0 {
+		return nil
+	}
+	cp := make(map[string][]EncryptionKey, len(s.Retired))
+	for b, keys := range s.Retired {
+		cp[b] = slices.Clone(keys)
+	}
+	return cp
+}
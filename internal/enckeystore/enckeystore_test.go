@@ -291,3 +291,84 @@ func TestAtomicWriteOverwritesFully(t *testing.T) {
 		t.Fatalf("after reload, missing 'bucket' key")
 	}
 }
+
+func TestRotateKeyFromDefault(t *testing.T) {
+	path := newTempStorePath(t)
+	s := NewEncryptionKeyStore(path)
+
+	def, err := s.AddDefaultKey("")
+	if err != nil {
+		t.Fatalf("AddDefaultKey = %v", err)
+	}
+	k, _ := GenerateEncryptionKey()
+
+	prev, err := s.RotateKey("photos", k)
+	if err != nil {
+		t.Fatalf("RotateKey(photos) = %v", err)
+	}
+	if prev != "" {
+		t.Fatalf("RotateKey(photos) prev = %q; want empty (bucket used default)", prev)
+	}
+	if got, _ := s.Get("photos"); got != k {
+		t.Fatalf("Get(photos) = %q; want new key", got)
+	}
+	if r := s.RetiredKeys("photos"); len(r) != 1 || r[0] != def {
+		t.Fatalf("RetiredKeys(photos) = %v; want [default]", r)
+	}
+
+	// retired keys survive save/load
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	s2 := NewEncryptionKeyStore(path)
+	if err := s2.Load(); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if r := s2.RetiredKeys("photos"); len(r) != 1 || r[0] != def {
+		t.Fatalf("RetiredKeys(photos) after reload = %v; want [default]", r)
+	}
+
+	s2.FinishRotation("photos")
+	if r := s2.RetiredKeys("photos"); len(r) != 0 {
+		t.Fatalf("RetiredKeys(photos) after finish = %v; want empty", r)
+	}
+	if s2.Keys["default"] != def {
+		t.Fatalf("default key changed by bucket rotation")
+	}
+}
+
+func TestCancelRotation(t *testing.T) {
+	path := newTempStorePath(t)
+	s := NewEncryptionKeyStore(path)
+
+	if _, err := s.AddDefaultKey(""); err != nil {
+		t.Fatalf("AddDefaultKey = %v", err)
+	}
+	old, _ := GenerateEncryptionKey()
+	if err := s.AddKey("logs", old); err != nil {
+		t.Fatalf("AddKey(logs) = %v", err)
+	}
+	k, _ := GenerateEncryptionKey()
+	prev, err := s.RotateKey("logs", k)
+	if err != nil {
+		t.Fatalf("RotateKey(logs) = %v", err)
+	}
+	if prev != old {
+		t.Fatalf("RotateKey(logs) prev = %q; want %q", prev, old)
+	}
+
+	s.CancelRotation("logs", prev)
+	if got, _ := s.Get("logs"); got != old {
+		t.Fatalf("Get(logs) after cancel = %q; want old key", got)
+	}
+	if r := s.RetiredKeys("logs"); len(r) != 0 {
+		t.Fatalf("RetiredKeys(logs) after cancel = %v; want empty", r)
+	}
+
+	// cancel for a bucket that used default removes its own key
+	prev, _ = s.RotateKey("photos", k)
+	s.CancelRotation("photos", prev)
+	if s.HasKey("photos") {
+		t.Fatalf("HasKey(photos) after cancel = true; want false")
+	}
+}
@@ -0,0 +1,58 @@
+package enckeystore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+var (
+	ErrPassphraseRequired = errors.New("encryption key store is protected by a passphrase")
+	ErrWrongPassphrase    = errors.New("wrong encryption key store passphrase")
+
+	errNestedSealed = errors.New("sealed key store contains another sealed section")
+)
+
+// scryptParams are used to seal the store, opening uses the parameters saved in the file.
+var scryptParams = encrypt.DefaultScryptParams
+
+// seal encrypts the store file with a key derived from passphrase.
+func seal(passphrase, plain []byte) (*encrypt.SealedBox, error) {
+	box, err := encrypt.Seal(passphrase, plain, scryptParams)
+	if err != nil {
+		return nil, fmt.Errorf("seal key store: %w", err)
+	}
+	return box, nil
+}
+
+// open decrypts the sealed store file with passphrase.
+func open(box *encrypt.SealedBox, passphrase []byte) ([]byte, error) {
+	plain, err := box.Open(passphrase)
+	if errors.Is(err, encrypt.ErrorWrongPassphrase) {
+		return nil, ErrWrongPassphrase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open key store: %w", err)
+	}
+	return plain, nil
+}
+
+// SetPassphrase sets the passphrase used to open a sealed store on Load
+// and to seal the store on Save. An empty passphrase makes Save write plaintext.
+func (s *EncryptionKeyStore) SetPassphrase(passphrase []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(passphrase) == 0 {
+		s.passphrase = nil
+		return
+	}
+	s.passphrase = append([]byte(nil), passphrase...)
+}
+
+// HasPassphrase reports whether the store is sealed with a passphrase on Save.
+func (s *EncryptionKeyStore) HasPassphrase() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.passphrase) > 0
+}
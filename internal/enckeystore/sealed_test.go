@@ -0,0 +1,170 @@
+package enckeystore
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	// fast parameters for tests
+	scryptParams = encrypt.ScryptParams{N: 1 << 10, R: 8, P: 1}
+}
+
+func TestSealedRoundTrip(t *testing.T) {
+	path := newTempStorePath(t)
+	s := NewEncryptionKeyStore(path)
+	s.SetPassphrase([]byte("correct horse"))
+
+	def, err := s.EnsureDefaultKey()
+	if err != nil {
+		t.Fatalf("EnsureDefaultKey() = %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read = %v", err)
+	}
+	if strings.Contains(string(raw), string(def)) || !strings.Contains(string(raw), "sealed:") {
+		t.Fatalf("sealed file holds plaintext keys:\n%s", raw)
+	}
+
+	s2 := NewEncryptionKeyStore(path)
+	s2.SetPassphrase([]byte("correct horse"))
+	if err := s2.Load(); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if s2.Keys["default"] != def {
+		t.Fatalf("default key after reload = %q; want %q", s2.Keys["default"], def)
+	}
+}
+
+func TestSealedLoadRequiresPassphrase(t *testing.T) {
+	path := newTempStorePath(t)
+	s := NewEncryptionKeyStore(path)
+	s.SetPassphrase([]byte("correct horse"))
+	if _, err := s.AddDefaultKey(""); err != nil {
+		t.Fatalf("AddDefaultKey = %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	s2 := NewEncryptionKeyStore(path)
+	if err := s2.Load(); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("Load() without passphrase = %v; want ErrPassphraseRequired", err)
+	}
+
+	s2.SetPassphrase([]byte("wrong"))
+	if err := s2.Load(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Load() with wrong passphrase = %v; want ErrWrongPassphrase", err)
+	}
+}
+
+func TestMigratePlaintextToSealedAndBack(t *testing.T) {
+	path := newTempStorePath(t)
+	s := NewEncryptionKeyStore(path)
+	def, _ := s.AddDefaultKey("")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() plaintext = %v", err)
+	}
+
+	// plaintext store loads without a passphrase and is sealed on save
+	s2 := NewEncryptionKeyStore(path)
+	if err := s2.Load(); err != nil {
+		t.Fatalf("Load() plaintext = %v", err)
+	}
+	s2.SetPassphrase([]byte("new passphrase"))
+	if err := s2.Save(); err != nil {
+		t.Fatalf("Save() sealed = %v", err)
+	}
+
+	s3 := NewEncryptionKeyStore(path)
+	if err := s3.Load(); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("Load() migrated store = %v; want ErrPassphraseRequired", err)
+	}
+	s3.SetPassphrase([]byte("new passphrase"))
+	if err := s3.Load(); err != nil || s3.Keys["default"] != def {
+		t.Fatalf("Load() migrated store = %v, default = %q", err, s3.Keys["default"])
+	}
+
+	// removing the passphrase writes plaintext again
+	s3.SetPassphrase(nil)
+	if err := s3.Save(); err != nil {
+		t.Fatalf("Save() plaintext = %v", err)
+	}
+	s4 := NewEncryptionKeyStore(path)
+	if err := s4.Load(); err != nil || s4.Keys["default"] != def {
+		t.Fatalf("Load() plaintext = %v, default = %q", err, s4.Keys["default"])
+	}
+}
+
+func TestSealedLoadScryptBounds(t *testing.T) {
+	path := newTempStorePath(t)
+	s := NewEncryptionKeyStore(path)
+	s.SetPassphrase([]byte("correct horse"))
+	if _, err := s.AddDefaultKey(""); err != nil {
+		t.Fatalf("AddDefaultKey = %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	// a crafted file asks for 2^30 iterations and 16 GiB of memory
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read = %v", err)
+	}
+	crafted := strings.Replace(string(raw), `"n": 1024`, `"n": 1073741824`, 1)
+	if crafted == string(raw) {
+		t.Fatalf("sealed file has no scrypt n parameter:\n%s", raw)
+	}
+	if err := os.WriteFile(path, []byte(crafted), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s2 := NewEncryptionKeyStore(path)
+	s2.SetPassphrase([]byte("correct horse"))
+	if err := s2.Load(); !errors.Is(err, encrypt.ErrorInvalidScryptParams) {
+		t.Fatalf("Load() crafted store = %v; want ErrorInvalidScryptParams", err)
+	}
+}
+
+func TestSealedLoadRejectsNestedSealed(t *testing.T) {
+	path := newTempStorePath(t)
+	passphrase := []byte("correct horse")
+
+	inner, err := seal(passphrase, []byte("keys: {}\n"))
+	if err != nil {
+		t.Fatalf("seal inner = %v", err)
+	}
+	innerYAML, err := yaml.Marshal(onDisk{Sealed: inner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err := seal(passphrase, innerYAML)
+	if err != nil {
+		t.Fatalf("seal outer = %v", err)
+	}
+	raw, err := yaml.Marshal(onDisk{Sealed: outer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewEncryptionKeyStore(path)
+	s.SetPassphrase(passphrase)
+	err = s.Load()
+	if !errors.Is(err, errNestedSealed) || strings.Contains(err.Error(), "%!") {
+		t.Fatalf("Load() nested sealed store = %v; want errNestedSealed", err)
+	}
+}
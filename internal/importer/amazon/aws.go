@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/spf13/pflag"
 )
 
 func New(ctx context.Context, profile, region, accessKeyID, accessKeySecret string) (aws.Config, error) {
@@ -46,3 +47,24 @@ func New(ctx context.Context, profile, region, accessKeyID, accessKeySecret stri
 
 	return cfg, nil
 }
+
+// Flags holds AWS credentials and region flags.
+type Flags struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	ProfileName     string
+	Region          string
+}
+
+// Register adds the AWS flags to a flag set.
+func (f *Flags) Register(fs *pflag.FlagSet) {
+	fs.StringVar(&f.AccessKeyID, "aws-key-id", "", "AWS Access Key ID")
+	fs.StringVar(&f.AccessKeySecret, "aws-secret-key", "", "AWS Secret Access Key")
+	fs.StringVar(&f.ProfileName, "aws-profile", "default", "AWS Profile name")
+	fs.StringVar(&f.Region, "aws-region", "", "AWS Region")
+}
+
+// Config loads the AWS config for the flags.
+func (f *Flags) Config(ctx context.Context) (aws.Config, error) {
+	return New(ctx, f.ProfileName, f.Region, f.AccessKeyID, f.AccessKeySecret)
+}
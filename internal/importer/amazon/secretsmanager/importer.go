@@ -0,0 +1,98 @@
+package secretsmanager
+
+import (
+	"context"
+
+	awsSm "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/spf13/pflag"
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/importer/amazon"
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+func init() {
+	importer.Register("secretsmanager", func() importer.Importer { return &Importer{} })
+}
+
+// Importer imports secrets from AWS Secrets Manager.
+type Importer struct {
+	// Client is used instead of a client created from the AWS flags when set.
+	Client Client
+
+	aws        amazon.Flags
+	tags       []string
+	expandJSON bool
+	flags      *pflag.FlagSet
+}
+
+// Info describes the importer for the command line.
+func (i *Importer) Info() importer.Info {
+	return importer.Info{
+		Use:   "[<prefix>]",
+		Short: "Import secrets from AWS Secrets Manager",
+		Long:  `Fetch secrets from AWS Secrets Manager and store them in the local kv bucket. Secrets are selected by name prefix and tags. Supports profile or access key authentication, key name trimming and expanding JSON secrets into one key per field. Binary secrets are stored base64 encoded.`,
+		Example: `
+  # Import all secrets with names under a prefix using the default profile
+  # Region must be set in ~/.aws/config, ~/.aws/credentials or AWS_REGION environment variable
+  kv import secretsmanager --bucket=mybucket prod/app/
+
+  # Use a specific AWS profile and region
+  kv import secretsmanager --bucket=mybucket --aws-profile=dev --aws-region=ca-central-1 dev/app/
+
+  # Import secrets with tags
+  kv import secretsmanager --bucket=mybucket --tag team=payments --tag env=prod
+
+  # Store every field of a JSON secret as a separate key
+  # e.g., prod/app/db {"username":"admin"} => prod/app/db/username
+  kv import secretsmanager --bucket=mybucket --expand-json prod/app/db
+
+  # Expand JSON secrets and trim key names
+  # e.g., prod/app/db {"username":"admin"} => username
+  kv import secretsmanager --bucket=mybucket --expand-json --trim-key-name prod/app/db
+
+  # Dry-run and show key values for verification
+  kv import secretsmanager --bucket=mybucket --dry-run --values prod/
+`,
+		MinArgs: 0,
+		MaxArgs: 1,
+	}
+}
+
+// Flags returns the AWS, tag filter and JSON expansion flags.
+func (i *Importer) Flags() *pflag.FlagSet {
+	if i.flags == nil {
+		i.flags = pflag.NewFlagSet("secretsmanager", pflag.ContinueOnError)
+		i.aws.Register(i.flags)
+		i.flags.StringArrayVar(&i.tags, "tag", nil, "Import only secrets with the tag (key=value or key), can be repeated")
+		i.flags.BoolVar(&i.expandJSON, "expand-json", false, "Store every field of a JSON secret as a separate key (e.g., prod/app/db → prod/app/db/username)")
+	}
+	return i.flags
+}
+
+// Fetch returns secrets with names starting with the optional prefix in args.
+func (i *Importer) Fetch(ctx context.Context, args []string) ([]models.Entity, error) {
+	opts := Options{ExpandJSON: i.expandJSON}
+	if len(args) > 0 {
+		opts.Prefix = args[0]
+	}
+	tags, err := utils.ParseTags(i.tags)
+	if err != nil {
+		return nil, err
+	}
+	opts.Tags = tags
+
+	client := i.Client
+	if client == nil {
+		cfg, err := i.aws.Config(ctx)
+		if err != nil {
+			return nil, err
+		}
+		client = awsSm.NewFromConfig(cfg)
+	}
+	secrets, err := GetSecrets(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	return importer.FromMap(secrets), nil
+}
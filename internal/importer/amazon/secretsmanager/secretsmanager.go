@@ -0,0 +1,146 @@
+package secretsmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+var (
+	ErrDuplicateKey = errors.New("secretsmanager: two secrets map to the same key")
+)
+
+// Client is the part of the Secrets Manager API used by the importer.
+type Client interface {
+	secretsmanager.ListSecretsAPIClient
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// Options selects secrets and sets how they are stored.
+type Options struct {
+	// Prefix selects secrets with names starting with it.
+	Prefix string
+	// Tags selects secrets that have all tags, an empty value matches any value of the tag.
+	Tags map[string]string
+	// ExpandJSON stores every field of a JSON object secret as a separate key, <name>/<field>.
+	ExpandJSON bool
+}
+
+// GetSecrets fetches secrets by name prefix and tags.
+// Binary secrets are stored base64 encoded. A field of an expanded JSON secret
+// with the same key as another secret returns ErrDuplicateKey.
+func GetSecrets(ctx context.Context, client Client, opts Options) (map[string]string, error) {
+	secrets := map[string]string{}
+
+	paginator := secretsmanager.NewListSecretsPaginator(client, &secretsmanager.ListSecretsInput{
+		Filters: filters(opts),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range page.SecretList {
+			name := aws.ToString(s.Name)
+			// service side filters are case-insensitive and don't pair tag keys with values
+			if !strings.HasPrefix(name, opts.Prefix) || !matchTags(s.Tags, opts.Tags) {
+				continue
+			}
+
+			out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: s.ARN})
+			if err != nil {
+				return nil, fmt.Errorf("get secret %s: %w", name, err)
+			}
+			value := aws.ToString(out.SecretString)
+			if out.SecretString == nil && out.SecretBinary != nil {
+				value = base64.StdEncoding.EncodeToString(out.SecretBinary)
+			}
+
+			fields := map[string]string{name: value}
+			if opts.ExpandJSON {
+				if f, ok := expandJSON(name, value); ok {
+					fields = f
+				}
+			}
+			for k, v := range fields {
+				if _, ok := secrets[k]; ok {
+					return nil, fmt.Errorf("%w: %s", ErrDuplicateKey, k)
+				}
+				secrets[k] = v
+			}
+		}
+	}
+
+	return secrets, nil
+}
+
+// filters returns ListSecrets filters for the options.
+func filters(opts Options) []types.Filter {
+	var f []types.Filter
+	if opts.Prefix != "" {
+		f = append(f, types.Filter{Key: types.FilterNameStringTypeName, Values: []string{opts.Prefix}})
+	}
+	for k, v := range opts.Tags {
+		f = append(f, types.Filter{Key: types.FilterNameStringTypeTagKey, Values: []string{k}})
+		if v != "" {
+			f = append(f, types.Filter{Key: types.FilterNameStringTypeTagValue, Values: []string{v}})
+		}
+	}
+	return f
+}
+
+// matchTags reports whether secret tags have all tags from filter.
+func matchTags(tags []types.Tag, filter map[string]string) bool {
+	for k, v := range filter {
+		found := false
+		for _, t := range tags {
+			if aws.ToString(t.Key) == k && (v == "" || aws.ToString(t.Value) == v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// expandJSON returns the fields of a JSON object secret as <name>/<field> keys.
+// Numbers and booleans are stored as text, nested objects and lists as JSON.
+// An empty object has no fields and is not expanded.
+func expandJSON(name string, value string) (map[string]string, bool) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &obj); err != nil || len(obj) == 0 {
+		return nil, false
+	}
+
+	fields := make(map[string]string, len(obj))
+	for k, raw := range obj {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			fields[name+"/"+k] = s
+			continue
+		}
+		if bytes.Equal(raw, []byte("null")) {
+			fields[name+"/"+k] = ""
+			continue
+		}
+		var b bytes.Buffer
+		if err := json.Compact(&b, raw); err != nil {
+			return nil, false
+		}
+		fields[name+"/"+k] = b.String()
+	}
+	return fields, true
+}
@@ -0,0 +1,162 @@
+package secretsmanager_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsSm "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/importer/amazon/secretsmanager"
+)
+
+type secret struct {
+	name   string
+	value  *string
+	binary []byte
+	tags   map[string]string
+}
+
+// stubClient serves secrets from memory, one secret per ListSecrets page.
+type stubClient struct {
+	secrets []secret
+	filters []types.Filter
+}
+
+func (c *stubClient) ListSecrets(ctx context.Context, in *awsSm.ListSecretsInput, _ ...func(*awsSm.Options)) (*awsSm.ListSecretsOutput, error) {
+	c.filters = in.Filters
+	i := 0
+	if in.NextToken != nil {
+		for i < len(c.secrets) && c.secrets[i].name != *in.NextToken {
+			i++
+		}
+	}
+	out := &awsSm.ListSecretsOutput{}
+	if i < len(c.secrets) {
+		s := c.secrets[i]
+		entry := types.SecretListEntry{Name: aws.String(s.name), ARN: aws.String("arn:" + s.name)}
+		for k, v := range s.tags {
+			entry.Tags = append(entry.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		out.SecretList = []types.SecretListEntry{entry}
+		if i+1 < len(c.secrets) {
+			out.NextToken = aws.String(c.secrets[i+1].name)
+		}
+	}
+	return out, nil
+}
+
+func (c *stubClient) GetSecretValue(ctx context.Context, in *awsSm.GetSecretValueInput, _ ...func(*awsSm.Options)) (*awsSm.GetSecretValueOutput, error) {
+	for _, s := range c.secrets {
+		if "arn:"+s.name == aws.ToString(in.SecretId) {
+			return &awsSm.GetSecretValueOutput{Name: aws.String(s.name), SecretString: s.value, SecretBinary: s.binary}, nil
+		}
+	}
+	return nil, errors.New("secret not found")
+}
+
+func newStub() *stubClient {
+	return &stubClient{secrets: []secret{
+		{name: "prod/app/db", value: aws.String(`{"username":"admin","password":"pw","port":5432,"opts":{"ssl": true},"none":null}`), tags: map[string]string{"team": "payments"}},
+		{name: "prod/app/token", value: aws.String("abc"), tags: map[string]string{"team": "ops"}},
+		{name: "prod/app/cert", binary: []byte{0x01, 0x02}},
+		{name: "Prod/other", value: aws.String("x")},
+	}}
+}
+
+func TestGetSecrets(t *testing.T) {
+	got, err := secretsmanager.GetSecrets(context.Background(), newStub(), secretsmanager.Options{Prefix: "prod/"})
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	want := map[string]string{
+		"prod/app/db":    `{"username":"admin","password":"pw","port":5432,"opts":{"ssl": true},"none":null}`,
+		"prod/app/token": "abc",
+		"prod/app/cert":  "AQI=",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSecrets() = %#v; want %#v", got, want)
+	}
+}
+
+func TestGetSecretsExpandJSON(t *testing.T) {
+	opts := secretsmanager.Options{Prefix: "prod/app/", ExpandJSON: true}
+	got, err := secretsmanager.GetSecrets(context.Background(), newStub(), opts)
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	want := map[string]string{
+		"prod/app/db/username": "admin",
+		"prod/app/db/password": "pw",
+		"prod/app/db/port":     "5432",
+		"prod/app/db/opts":     `{"ssl":true}`,
+		"prod/app/db/none":     "",
+		"prod/app/token":       "abc",
+		"prod/app/cert":        "AQI=",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSecrets() = %#v; want %#v", got, want)
+	}
+}
+
+func TestGetSecretsExpandJSONEmptyObject(t *testing.T) {
+	c := &stubClient{secrets: []secret{{name: "prod/app/empty", value: aws.String("{}")}}}
+	got, err := secretsmanager.GetSecrets(context.Background(), c, secretsmanager.Options{ExpandJSON: true})
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"prod/app/empty": "{}"}) {
+		t.Errorf("GetSecrets() = %#v", got)
+	}
+}
+
+func TestGetSecretsExpandJSONDuplicateKey(t *testing.T) {
+	// an expanded field and a separate secret in either order
+	for _, secrets := range [][]secret{
+		{{name: "prod/app/db", value: aws.String(`{"password":"pw"}`)}, {name: "prod/app/db/password", value: aws.String("other")}},
+		{{name: "prod/app/db/password", value: aws.String("other")}, {name: "prod/app/db", value: aws.String(`{"password":"pw"}`)}},
+	} {
+		c := &stubClient{secrets: secrets}
+		_, err := secretsmanager.GetSecrets(context.Background(), c, secretsmanager.Options{ExpandJSON: true})
+		if !errors.Is(err, secretsmanager.ErrDuplicateKey) {
+			t.Errorf("GetSecrets() error = %v; want ErrDuplicateKey", err)
+		}
+	}
+}
+
+func TestGetSecretsByTag(t *testing.T) {
+	c := newStub()
+	opts := secretsmanager.Options{Tags: map[string]string{"team": "ops"}}
+	got, err := secretsmanager.GetSecrets(context.Background(), c, opts)
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"prod/app/token": "abc"}) {
+		t.Errorf("GetSecrets() = %#v", got)
+	}
+	if len(c.filters) != 2 || c.filters[0].Key != types.FilterNameStringTypeTagKey || c.filters[1].Key != types.FilterNameStringTypeTagValue {
+		t.Errorf("unexpected filters: %+v", c.filters)
+	}
+}
+
+func TestImporter(t *testing.T) {
+	imp, err := importer.New("secretsmanager")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	imp.(*secretsmanager.Importer).Client = newStub()
+	if err := imp.Flags().Parse([]string{"--tag", "team=payments", "--expand-json"}); err != nil {
+		t.Fatalf("Parse flags failed: %v", err)
+	}
+
+	entities, err := imp.Fetch(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entities) != 5 || entities[0].Key != "prod/app/db/none" {
+		t.Errorf("Fetch() = %v", entities)
+	}
+}
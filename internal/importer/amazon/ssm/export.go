@@ -0,0 +1,161 @@
+package ssm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+var (
+	ErrConflict       = errors.New("parameter exists with another value")
+	ErrInvalidKeyName = errors.New("key name can't be mapped to a parameter name")
+)
+
+// deleteBatchSize is the maximum number of parameters in a DeleteParameters request.
+const deleteBatchSize = 10
+
+// Action is a change of a parameter made by PutSecrets.
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	ActionConflict  Action = "conflict" // exists with another value, not overwritten
+	ActionDelete    Action = "delete"
+)
+
+// Change describes what PutSecrets does with a parameter.
+type Change struct {
+	Name   string `json:"name"`
+	Key    string `json:"key,omitempty"` // kv key name, empty for deleted parameters
+	Action Action `json:"action"`
+}
+
+// ExportOptions sets how PutSecrets writes parameters.
+type ExportOptions struct {
+	// KMSKeyID encrypts parameters with a customer managed key instead of the AWS managed key.
+	KMSKeyID string
+	// Overwrite updates existing parameters with another value.
+	Overwrite bool
+	// Prune deletes parameters under the path that are not in secrets.
+	Prune bool
+	// DryRun returns the changes without applying them.
+	DryRun bool
+}
+
+// ParameterName returns the parameter name for a key under a path.
+// A key starting with "/" is rejected, it would map to the same parameter as the key without it
+// and KeyName could not return it.
+func ParameterName(path string, key string) (string, error) {
+	if strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("%w: %q starts with \"/\"", ErrInvalidKeyName, key)
+	}
+	return strings.TrimSuffix(path, "/") + "/" + key, nil
+}
+
+// KeyName returns the key name of a parameter under a path, the reverse of ParameterName.
+func KeyName(path string, name string) string {
+	return strings.TrimPrefix(name, strings.TrimSuffix(path, "/")+"/")
+}
+
+// GetKeys fetches all parameters under a path recursively, keyed by their key names.
+func GetKeys(ctx context.Context, client ssm.GetParametersByPathAPIClient, path string) (map[string]string, error) {
+	params, err := GetSecrets(ctx, client, path, true, false)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string, len(params))
+	for name, v := range params {
+		keys[KeyName(path, name)] = v
+	}
+	return keys, nil
+}
+
+// PutSecrets writes secrets as SecureString parameters named <path>/<key>.
+// Parameters with the same value are not written again. It returns the changes, sorted by name.
+// Nothing is written if a key can't be mapped to a parameter name.
+func PutSecrets(ctx context.Context, client Client, path string, secrets map[string]string, opts ExportOptions) ([]Change, error) {
+	names := make(map[string]string, len(secrets))
+	for key := range secrets {
+		name, err := ParameterName(path, key)
+		if err != nil {
+			return nil, err
+		}
+		names[key] = name
+	}
+
+	existing, err := GetSecrets(ctx, client, path, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("get parameters: %w", err)
+	}
+
+	var changes []Change
+	wanted := make(map[string]bool, len(secrets))
+	for _, key := range slices.Sorted(maps.Keys(secrets)) {
+		name := names[key]
+		wanted[name] = true
+
+		action := ActionCreate
+		if cur, ok := existing[name]; ok {
+			switch {
+			case cur == secrets[key]:
+				action = ActionUnchanged
+			case opts.Overwrite:
+				action = ActionUpdate
+			default:
+				action = ActionConflict
+			}
+		}
+		changes = append(changes, Change{Name: name, Key: key, Action: action})
+	}
+	if opts.Prune {
+		for _, name := range slices.Sorted(maps.Keys(existing)) {
+			if !wanted[name] {
+				changes = append(changes, Change{Name: name, Action: ActionDelete})
+			}
+		}
+	}
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	var deletes []string
+	for _, c := range changes {
+		switch c.Action {
+		case ActionCreate, ActionUpdate:
+			in := &ssm.PutParameterInput{
+				Name:      aws.String(c.Name),
+				Value:     aws.String(secrets[c.Key]),
+				Type:      types.ParameterTypeSecureString,
+				Overwrite: aws.Bool(c.Action == ActionUpdate),
+			}
+			if opts.KMSKeyID != "" {
+				in.KeyId = aws.String(opts.KMSKeyID)
+			}
+			if _, err := client.PutParameter(ctx, in); err != nil {
+				return nil, fmt.Errorf("put parameter %s: %w", c.Name, err)
+			}
+		case ActionDelete:
+			deletes = append(deletes, c.Name)
+		}
+	}
+	for batch := range slices.Chunk(deletes, deleteBatchSize) {
+		out, err := client.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: batch})
+		if err != nil {
+			return nil, fmt.Errorf("delete parameters: %w", err)
+		}
+		if len(out.InvalidParameters) > 0 {
+			return nil, fmt.Errorf("delete parameters: invalid parameters: %s", strings.Join(out.InvalidParameters, ", "))
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,183 @@
+package ssm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsSsm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+)
+
+// stubClient keeps parameters in memory.
+type stubClient struct {
+	params  map[string]string
+	keyIDs  map[string]string
+	deletes [][]string
+}
+
+func (c *stubClient) GetParametersByPath(ctx context.Context, in *awsSsm.GetParametersByPathInput, _ ...func(*awsSsm.Options)) (*awsSsm.GetParametersByPathOutput, error) {
+	out := &awsSsm.GetParametersByPathOutput{}
+	for _, name := range slices.Sorted(maps.Keys(c.params)) {
+		if strings.HasPrefix(name, aws.ToString(in.Path)) {
+			out.Parameters = append(out.Parameters, types.Parameter{Name: aws.String(name), Value: aws.String(c.params[name])})
+		}
+	}
+	return out, nil
+}
+
+func (c *stubClient) PutParameter(ctx context.Context, in *awsSsm.PutParameterInput, _ ...func(*awsSsm.Options)) (*awsSsm.PutParameterOutput, error) {
+	name := aws.ToString(in.Name)
+	if _, ok := c.params[name]; ok && !aws.ToBool(in.Overwrite) {
+		return nil, fmt.Errorf("parameter %s already exists", name)
+	}
+	if in.Type != types.ParameterTypeSecureString {
+		return nil, fmt.Errorf("unexpected type %s", in.Type)
+	}
+	c.params[name] = aws.ToString(in.Value)
+	c.keyIDs[name] = aws.ToString(in.KeyId)
+	return &awsSsm.PutParameterOutput{}, nil
+}
+
+func (c *stubClient) DeleteParameters(ctx context.Context, in *awsSsm.DeleteParametersInput, _ ...func(*awsSsm.Options)) (*awsSsm.DeleteParametersOutput, error) {
+	c.deletes = append(c.deletes, in.Names)
+	for _, name := range in.Names {
+		delete(c.params, name)
+	}
+	return &awsSsm.DeleteParametersOutput{DeletedParameters: in.Names}, nil
+}
+
+func newStub() *stubClient {
+	return &stubClient{
+		params: map[string]string{
+			"/prod/app/same":  "1",
+			"/prod/app/other": "old",
+			"/prod/app/gone":  "x",
+			"/stage/app/keep": "y",
+		},
+		keyIDs: map[string]string{},
+	}
+}
+
+var secrets = map[string]string{"same": "1", "other": "new", "added": "2"}
+
+func actions(changes []ssm.Change) map[string]ssm.Action {
+	out := make(map[string]ssm.Action, len(changes))
+	for _, c := range changes {
+		out[c.Name] = c.Action
+	}
+	return out
+}
+
+func TestPutSecrets(t *testing.T) {
+	c := newStub()
+	changes, err := ssm.PutSecrets(context.Background(), c, "/prod/app/", secrets, ssm.ExportOptions{KMSKeyID: "alias/kv"})
+	if err != nil {
+		t.Fatalf("PutSecrets failed: %v", err)
+	}
+	want := map[string]ssm.Action{
+		"/prod/app/added": ssm.ActionCreate,
+		"/prod/app/other": ssm.ActionConflict,
+		"/prod/app/same":  ssm.ActionUnchanged,
+	}
+	if got := actions(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v; want %v", got, want)
+	}
+	if c.params["/prod/app/added"] != "2" || c.params["/prod/app/other"] != "old" || c.params["/prod/app/gone"] != "x" {
+		t.Errorf("unexpected parameters: %v", c.params)
+	}
+	if c.keyIDs["/prod/app/added"] != "alias/kv" {
+		t.Errorf("KMS key id not set: %v", c.keyIDs)
+	}
+}
+
+func TestPutSecretsOverwriteAndPrune(t *testing.T) {
+	c := newStub()
+	changes, err := ssm.PutSecrets(context.Background(), c, "/prod/app", secrets, ssm.ExportOptions{Overwrite: true, Prune: true})
+	if err != nil {
+		t.Fatalf("PutSecrets failed: %v", err)
+	}
+	want := map[string]ssm.Action{
+		"/prod/app/added": ssm.ActionCreate,
+		"/prod/app/other": ssm.ActionUpdate,
+		"/prod/app/same":  ssm.ActionUnchanged,
+		"/prod/app/gone":  ssm.ActionDelete,
+	}
+	if got := actions(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v; want %v", got, want)
+	}
+	wantParams := map[string]string{
+		"/prod/app/same":  "1",
+		"/prod/app/other": "new",
+		"/prod/app/added": "2",
+		"/stage/app/keep": "y",
+	}
+	if !reflect.DeepEqual(c.params, wantParams) {
+		t.Errorf("parameters = %v; want %v", c.params, wantParams)
+	}
+}
+
+func TestPutSecretsDryRun(t *testing.T) {
+	c := newStub()
+	before := maps.Clone(c.params)
+	changes, err := ssm.PutSecrets(context.Background(), c, "/prod/app/", secrets, ssm.ExportOptions{Overwrite: true, Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("PutSecrets failed: %v", err)
+	}
+	if len(changes) != 4 {
+		t.Errorf("expected 4 changes, got %v", changes)
+	}
+	if !reflect.DeepEqual(c.params, before) || len(c.deletes) != 0 {
+		t.Errorf("dry run changed parameters: %v", c.params)
+	}
+}
+
+func TestPutSecretsPruneBatches(t *testing.T) {
+	c := &stubClient{params: map[string]string{}, keyIDs: map[string]string{}}
+	for i := range 25 {
+		c.params[fmt.Sprintf("/old/k%02d", i)] = "v"
+	}
+	if _, err := ssm.PutSecrets(context.Background(), c, "/old", nil, ssm.ExportOptions{Prune: true}); err != nil {
+		t.Fatalf("PutSecrets failed: %v", err)
+	}
+	if len(c.params) != 0 || len(c.deletes) != 3 || len(c.deletes[0]) != 10 {
+		t.Errorf("unexpected deletes: %v, left: %v", c.deletes, c.params)
+	}
+}
+
+func TestGetKeys(t *testing.T) {
+	got, err := ssm.GetKeys(context.Background(), newStub(), "/prod/app/")
+	if err != nil {
+		t.Fatalf("GetKeys failed: %v", err)
+	}
+	want := map[string]string{"same": "1", "other": "old", "gone": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetKeys() = %v; want %v", got, want)
+	}
+	if n, _ := ssm.ParameterName("/prod/app", "db/pass"); ssm.KeyName("/prod/app/", n) != "db/pass" {
+		t.Errorf("KeyName(ParameterName()) = %s", ssm.KeyName("/prod/app/", n))
+	}
+}
+
+func TestParameterNameLeadingSlash(t *testing.T) {
+	if _, err := ssm.ParameterName("/prod/app", "/foo"); !errors.Is(err, ssm.ErrInvalidKeyName) {
+		t.Errorf("Expected ErrInvalidKeyName, got: %v", err)
+	}
+
+	// nothing is written when a key is rejected
+	c := newStub()
+	_, err := ssm.PutSecrets(context.Background(), c, "/prod/app", map[string]string{"a": "1", "/foo": "2"}, ssm.ExportOptions{})
+	if !errors.Is(err, ssm.ErrInvalidKeyName) {
+		t.Fatalf("Expected ErrInvalidKeyName, got: %v", err)
+	}
+	if _, ok := c.params["/prod/app/a"]; ok {
+		t.Error("PutSecrets wrote a parameter before rejecting a key")
+	}
+}
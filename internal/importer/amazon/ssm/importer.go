@@ -0,0 +1,95 @@
+package ssm
+
+import (
+	"context"
+
+	awsSsm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/pflag"
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/importer/amazon"
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+func init() {
+	importer.Register("ssm", func() importer.Importer { return &Importer{} })
+}
+
+// Importer imports parameters from AWS SSM Parameter Store.
+type Importer struct {
+	// Client is used instead of a client created from the AWS flags when set.
+	Client awsSsm.GetParametersByPathAPIClient
+
+	aws       amazon.Flags
+	recursive bool
+	flags     *pflag.FlagSet
+}
+
+// Info describes the importer for the command line.
+func (i *Importer) Info() importer.Info {
+	return importer.Info{
+		Use:   "<path>",
+		Short: "Import secrets from AWS SSM Parameter Store",
+		Long:  `Fetch parameters from AWS Systems Manager (SSM) Parameter Store and store them in the local kv bucket. Supports profile or access key authentication, recursive path scanning, and key name trimming.`,
+		Example: `
+  # Import all secrets under a path using the default profile
+  # Region must be set in ~/.aws/config, ~/.aws/credentials or AWS_REGION environment variable
+  kv import ssm --bucket=mybucket /prod/secrets
+
+  # Use a specific AWS profile and region
+  kv import ssm --bucket=mybucket --aws-profile=dev --aws-region=ca-central-1 /dev/app/config
+
+  # Use static access key credentials (profile is ignored)
+  # Can use a default AWS CLI environment variable
+  # https://docs.aws.amazon.com/cli/v1/userguide/cli-configure-envvars.html#envvars-set
+  kv import ssm --bucket=mybucket --aws-key-id=AKIA... --aws-secret-key=... --aws-region=us-west-2 /prod/secure
+
+  # Recursively import full key names under a prefix
+  # e.g., /prod/env/database_password
+  kv import ssm --bucket=mybucket --recursive /prod/env/
+
+  # Recursively import and trim key names
+  # e.g., /prod/env/database_password => database_password
+  kv import ssm --bucket=mybucket --recursive --trim-key-name /prod/env/
+
+  # Perform a dry-run import without writing data
+  kv import ssm --bucket=mybucket --dry-run /dev
+
+  # Dry-run and show key values for verification
+  kv import ssm --bucket=mybucket --dry-run --values /prod
+`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+// Flags returns the AWS and path scanning flags.
+func (i *Importer) Flags() *pflag.FlagSet {
+	if i.flags == nil {
+		i.flags = pflag.NewFlagSet("ssm", pflag.ContinueOnError)
+		i.aws.Register(i.flags)
+		i.flags.BoolVarP(&i.recursive, "recursive", "r", false, "Recursive import")
+	}
+	return i.flags
+}
+
+// Fetch returns parameters under the path given in args.
+func (i *Importer) Fetch(ctx context.Context, args []string) ([]models.Entity, error) {
+	path := args[0]
+	if path == "" {
+		path = "/"
+	}
+
+	client := i.Client
+	if client == nil {
+		cfg, err := i.aws.Config(ctx)
+		if err != nil {
+			return nil, err
+		}
+		client = awsSsm.NewFromConfig(cfg)
+	}
+	secrets, err := GetSecrets(ctx, client, path, i.recursive, false)
+	if err != nil {
+		return nil, err
+	}
+	return importer.FromMap(secrets), nil
+}
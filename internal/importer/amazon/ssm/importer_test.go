@@ -0,0 +1,28 @@
+package ssm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/importer/amazon/ssm"
+)
+
+func TestImporter(t *testing.T) {
+	imp, err := importer.New("ssm")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	imp.(*ssm.Importer).Client = newStub()
+	if err := imp.Flags().Parse([]string{"--recursive"}); err != nil {
+		t.Fatalf("Parse flags failed: %v", err)
+	}
+
+	entities, err := imp.Fetch(context.Background(), []string{"/prod/app/"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entities) != 3 || entities[0].Key != "/prod/app/gone" || entities[0].Value != "x" {
+		t.Errorf("Fetch() = %v", entities)
+	}
+}
@@ -8,8 +8,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 )
 
+// Client is the part of the SSM API used to import and export parameters.
+type Client interface {
+	ssm.GetParametersByPathAPIClient
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+	DeleteParameters(ctx context.Context, params *ssm.DeleteParametersInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParametersOutput, error)
+}
+
 // GetSecrets fetches parameters by prefix
-func GetSecrets(ctx context.Context, client *ssm.Client, path string, recursive bool, trimKeyName bool) (map[string]string, error) {
+func GetSecrets(ctx context.Context, client ssm.GetParametersByPathAPIClient, path string, recursive bool, trimKeyName bool) (map[string]string, error) {
 	secrets := map[string]string{}
 
 	paginator := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
@@ -30,8 +37,6 @@ func GetSecrets(ctx context.Context, client *ssm.Client, path string, recursive
 
 			if trimKeyName {
 				name = name[strings.LastIndex(name, "/")+1:]
-			} else {
-				name = name
 			}
 			secrets[name] = value
 		}
@@ -1,4 +1,14 @@
 /*
 Importers of secrets
+
+Every source implements Importer and registers itself by name in init,
+"kv import <name>" resolves the source from the registry:
+
+	func init() {
+		importer.Register("ssm", func() importer.Importer { return &Importer{} })
+	}
+
+Sources only fetch secrets. Dry-run, value display, conflict handling
+and key name mapping (KeyMapping) are shared by all sources.
 */
 package importer
@@ -0,0 +1,225 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported file formats.
+const (
+	FormatDotenv     = "dotenv"
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+	FormatProperties = "properties"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown file format, expected dotenv, json, yaml or properties")
+)
+
+// DetectFormat returns the file format by the file name extension.
+func DetectFormat(path string) (string, error) {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env"):
+		return FormatDotenv, nil
+	case strings.HasSuffix(base, ".json"):
+		return FormatJSON, nil
+	case strings.HasSuffix(base, ".yaml") || strings.HasSuffix(base, ".yml"):
+		return FormatYAML, nil
+	case strings.HasSuffix(base, ".properties"):
+		return FormatProperties, nil
+	}
+	return "", fmt.Errorf("%w: can't detect format of %s, use --format", ErrUnknownFormat, path)
+}
+
+// GetSecrets parses file content in the given format into key values.
+//
+// JSON and YAML files are either an object of key values, or a list of entities
+// with key and value fields as written by "kv list keys --format=json --values".
+// Numbers and booleans are stored as text, nested objects and lists as JSON.
+func GetSecrets(data []byte, format string) (map[string]string, error) {
+	switch format {
+	case FormatDotenv:
+		return utils.ParseDotenv(string(data))
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("decode json: %w", err)
+		}
+		return fromDocument(v)
+	case FormatYAML:
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("decode yaml: %w", err)
+		}
+		return fromDocument(v)
+	case FormatProperties:
+		return parseProperties(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// fromDocument converts a decoded JSON or YAML document into key values.
+func fromDocument(doc any) (map[string]string, error) {
+	secrets := make(map[string]string)
+	switch d := doc.(type) {
+	case map[string]any:
+		for k, v := range d {
+			s, err := toString(v)
+			if err != nil {
+				return nil, fmt.Errorf("key '%s': %w", k, err)
+			}
+			secrets[k] = s
+		}
+	case []any:
+		for i, item := range d {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			var e models.Entity
+			if err := json.Unmarshal(data, &e); err != nil || e.Key == "" {
+				return nil, fmt.Errorf("item %d: expected an object with key and value", i)
+			}
+			secrets[e.Key] = e.Value
+		}
+	case nil:
+	default:
+		return nil, errors.New("expected an object of key values or a list of entities")
+	}
+	return secrets, nil
+}
+
+// toString converts a decoded value to text.
+func toString(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(t), nil
+	case map[string]any, []any:
+		data, err := json.Marshal(t)
+		return string(data), err
+	}
+	return fmt.Sprint(v), nil
+}
+
+// parseProperties parses Java .properties content: key=value, key: value or key value lines,
+// "#" and "!" comments, line continuation with a trailing backslash and backslash escapes.
+func parseProperties(data []byte) (map[string]string, error) {
+	secrets := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+
+	for sc.Scan() {
+		line++
+		s := strings.TrimLeft(sc.Text(), " \t\f")
+		if s == "" || s[0] == '#' || s[0] == '!' {
+			continue
+		}
+		// join continuation lines, an even number of trailing backslashes is literal
+		for continues(s) && sc.Scan() {
+			line++
+			s = s[:len(s)-1] + strings.TrimLeft(sc.Text(), " \t\f")
+		}
+
+		key, value := splitProperty(s)
+		k, err := unescapeProperty(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		v, err := unescapeProperty(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		secrets[k] = v
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// continues reports whether a properties line ends with an unescaped backslash.
+func continues(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a properties line at the first unescaped '=', ':' or whitespace.
+func splitProperty(s string) (key string, value string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return s[:i], strings.TrimLeft(s[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(s[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+			return s[:i], rest
+		}
+	}
+	return s, ""
+}
+
+// unescapeProperty processes backslash escapes of a properties key or value.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,122 @@
+package file_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/importer/file"
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]string{
+		".env":                 file.FormatDotenv,
+		"/app/.env.production": file.FormatDotenv,
+		"prod.env":             file.FormatDotenv,
+		"secrets.json":         file.FormatJSON,
+		"config.yml":           file.FormatYAML,
+		"config.YAML":          file.FormatYAML,
+		"app.properties":       file.FormatProperties,
+	}
+	for path, want := range cases {
+		got, err := file.DetectFormat(path)
+		if err != nil || got != want {
+			t.Errorf("DetectFormat(%s) = %s, %v; want %s", path, got, err, want)
+		}
+	}
+	if _, err := file.DetectFormat("secrets.txt"); !errors.Is(err, file.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got: %v", err)
+	}
+}
+
+func TestGetSecrets(t *testing.T) {
+	cases := []struct {
+		format string
+		data   string
+		want   map[string]string
+	}{
+		{
+			file.FormatDotenv,
+			"DB_HOST=localhost\nDB_PASS=\"p\\nw\"\n",
+			map[string]string{"DB_HOST": "localhost", "DB_PASS": "p\nw"},
+		},
+		{
+			file.FormatJSON,
+			`{"host":"localhost","port":5432,"debug":false,"big":12345678901234567890,"tls":{"on":true},"empty":null}`,
+			map[string]string{"host": "localhost", "port": "5432", "debug": "false", "big": "12345678901234567890", "tls": `{"on":true}`, "empty": ""},
+		},
+		{
+			file.FormatJSON,
+			`[{"key":"a","value":"1","tags":{"team":"ops"}},{"key":"b"}]`,
+			map[string]string{"a": "1", "b": ""},
+		},
+		{
+			file.FormatYAML,
+			"host: localhost\nport: 5432\ncert: |\n  line1\n  line2\nlist: [a, b]\n",
+			map[string]string{"host": "localhost", "port": "5432", "cert": "line1\nline2\n", "list": `["a","b"]`},
+		},
+		{
+			file.FormatYAML,
+			"- key: a\n  value: \"1\"\n",
+			map[string]string{"a": "1"},
+		},
+		{
+			file.FormatProperties,
+			"# comment\n! comment\ndb.host=localhost\ndb.port : 5432\nname John Doe\nmulti=one \\\n    two\nkey\\=with\\:sep=v\nuni=\\u00e9\nempty=\n",
+			map[string]string{"db.host": "localhost", "db.port": "5432", "name": "John Doe", "multi": "one two", "key=with:sep": "v", "uni": "é", "empty": ""},
+		},
+	}
+	for _, c := range cases {
+		got, err := file.GetSecrets([]byte(c.data), c.format)
+		if err != nil {
+			t.Errorf("GetSecrets(%s) failed: %v", c.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("GetSecrets(%s) = %#v; want %#v", c.format, got, c.want)
+		}
+	}
+}
+
+func TestGetSecretsErrors(t *testing.T) {
+	cases := []struct {
+		format string
+		data   string
+	}{
+		{file.FormatJSON, `{"a":`},
+		{file.FormatJSON, `"string"`},
+		{file.FormatJSON, `[{"value":"no key"}]`},
+		{file.FormatYAML, "a: [b"},
+		{file.FormatProperties, `a=\u12`},
+		{"xml", `<a/>`},
+	}
+	for _, c := range cases {
+		if _, err := file.GetSecrets([]byte(c.data), c.format); err == nil {
+			t.Errorf("GetSecrets(%s, %q) expected error", c.format, c.data)
+		}
+	}
+}
+
+func TestImporter(t *testing.T) {
+	imp, err := importer.New("file")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	imp.(*file.Importer).Stdin = strings.NewReader("b: 2\na: 1\n")
+	if err := imp.Flags().Parse([]string{"--format", "yaml"}); err != nil {
+		t.Fatalf("Parse flags failed: %v", err)
+	}
+
+	entities, err := imp.Fetch(context.Background(), []string{"-"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	want := []models.Entity{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	if !reflect.DeepEqual(entities, want) {
+		t.Errorf("Fetch() = %v; want %v", entities, want)
+	}
+}
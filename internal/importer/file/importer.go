@@ -0,0 +1,92 @@
+package file
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+func init() {
+	importer.Register("file", func() importer.Importer { return &Importer{Stdin: os.Stdin} })
+}
+
+// Importer imports secrets from a dotenv, JSON, YAML or properties file.
+type Importer struct {
+	// Stdin is read for the "-" path.
+	Stdin io.Reader
+
+	format string
+	flags  *pflag.FlagSet
+}
+
+// Info describes the importer for the command line.
+func (i *Importer) Info() importer.Info {
+	return importer.Info{
+		Use:   "<path>",
+		Short: "Import secrets from a dotenv, JSON, YAML or properties file",
+		Long: `Read key values from a file and store them in the local kv bucket.
+
+Supported formats:
+  dotenv      KEY=value lines, both escaped and multiline (Rails) quoted values
+  json        an object of key values, or a list of entities from "kv list keys --format=json --values"
+  yaml        an object of key values, or a list of entities with key and value fields
+  properties  Java properties: key=value, key: value or key value lines
+
+The format is detected by the file extension, use --format to set it explicitly.
+Numbers and booleans are stored as text, nested objects and lists as JSON.`,
+		Example: `
+  kv import file --bucket=myservice .env
+  kv import file --bucket=myservice --format=json secrets.json
+  kv import file --bucket=myservice --format=yaml - < secrets.yaml
+
+  # Perform a dry-run import and show key values
+  kv import file --bucket=myservice --dry-run --values .env.production`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+// Flags returns the file format flag.
+func (i *Importer) Flags() *pflag.FlagSet {
+	if i.flags == nil {
+		i.flags = pflag.NewFlagSet("file", pflag.ContinueOnError)
+		i.flags.StringVarP(&i.format, "format", "f", "", "File format [dotenv, json, yaml, properties], detected by extension if not set")
+	}
+	return i.flags
+}
+
+// Fetch returns secrets from the file given in args, "-" reads from Stdin.
+func (i *Importer) Fetch(ctx context.Context, args []string) ([]models.Entity, error) {
+	path := args[0]
+
+	format := i.format
+	if format == "" {
+		var err error
+		if format, err = DetectFormat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(i.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := GetSecrets(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return importer.FromMap(secrets), nil
+}
@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/spf13/pflag"
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+var (
+	ErrUnknownSource = errors.New("unknown import source")
+)
+
+// Info describes an importer for the command line.
+type Info struct {
+	Use     string // positional arguments usage, e.g. "<path>"
+	Short   string
+	Long    string
+	Example string
+	MinArgs int
+	MaxArgs int
+}
+
+// Importer fetches secrets from an external source.
+type Importer interface {
+	// Info describes the source for the command line.
+	Info() Info
+	// Flags returns source specific flags, they are parsed before Fetch is called.
+	Flags() *pflag.FlagSet
+	// Fetch returns secrets for the positional arguments.
+	Fetch(ctx context.Context, args []string) ([]models.Entity, error)
+}
+
+// Factory creates an importer.
+type Factory func() Importer
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes an importer available by name.
+// It panics if an importer with the name is already registered.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("importer: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates a registered importer.
+func New(name string) (Importer, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSource, name)
+	}
+	return factory(), nil
+}
+
+// Names returns the sorted names of registered importers.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Sorted(maps.Keys(factories))
+}
+
+// FromMap converts key values to entities sorted by key.
+func FromMap(secrets map[string]string) []models.Entity {
+	entities := make([]models.Entity, 0, len(secrets))
+	for _, k := range slices.Sorted(maps.Keys(secrets)) {
+		entities = append(entities, models.Entity{Key: k, Value: secrets[k]})
+	}
+	return entities
+}
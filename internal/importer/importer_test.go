@@ -0,0 +1,102 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+type fakeImporter struct {
+	flags *pflag.FlagSet
+}
+
+func (f *fakeImporter) Info() importer.Info { return importer.Info{Short: "fake"} }
+
+func (f *fakeImporter) Flags() *pflag.FlagSet {
+	if f.flags == nil {
+		f.flags = pflag.NewFlagSet("fake", pflag.ContinueOnError)
+	}
+	return f.flags
+}
+
+func (f *fakeImporter) Fetch(ctx context.Context, args []string) ([]models.Entity, error) {
+	return importer.FromMap(map[string]string{"b": "2", "a": "1"}), nil
+}
+
+func TestRegistry(t *testing.T) {
+	importer.Register("fake", func() importer.Importer { return &fakeImporter{} })
+
+	if !slices.Contains(importer.Names(), "fake") {
+		t.Errorf("Names() = %v; want to contain fake", importer.Names())
+	}
+	imp, err := importer.New("fake")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	entities, err := imp.Fetch(context.Background(), nil)
+	if err != nil || len(entities) != 2 || entities[0].Key != "a" {
+		t.Errorf("Fetch() = %v, %v", entities, err)
+	}
+
+	if _, err := importer.New("missing"); !errors.Is(err, importer.ErrUnknownSource) {
+		t.Errorf("Expected ErrUnknownSource, got: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate Register")
+		}
+	}()
+	importer.Register("fake", func() importer.Importer { return &fakeImporter{} })
+}
+
+func mustRename(t *testing.T, s string) importer.Rename {
+	r, err := importer.ParseRename(s)
+	if err != nil {
+		t.Fatalf("ParseRename(%s) failed: %v", s, err)
+	}
+	return r
+}
+
+func TestKeyMapping(t *testing.T) {
+	m := importer.KeyMapping{
+		TrimPath: true,
+		Rename:   []importer.Rename{mustRename(t, `^db_(.*)$=database_$1`), mustRename(t, `-=_`)},
+		Prefix:   "APP_",
+	}
+	cases := map[string]string{
+		"/prod/env/db_password": "APP_database_password",
+		"api-token":             "APP_api_token",
+		"plain":                 "APP_plain",
+	}
+	for in, want := range cases {
+		if got := m.Key(in); got != want {
+			t.Errorf("Key(%s) = %s; want %s", in, got, want)
+		}
+	}
+
+	entities := []models.Entity{{Key: "/a/token", Value: "1"}, {Key: "/b/token", Value: "2"}}
+	if _, err := (importer.KeyMapping{TrimPath: true}).Apply(entities); err == nil {
+		t.Error("Expected error for keys mapped to the same name")
+	}
+	got, err := (importer.KeyMapping{Prefix: "x/"}).Apply(entities)
+	if err != nil || got[0].Key != "x//a/token" || got[1].Value != "2" {
+		t.Errorf("Apply() = %v, %v", got, err)
+	}
+	if _, err := (importer.KeyMapping{Rename: []importer.Rename{mustRename(t, `.*=`)}}).Apply(entities); err == nil {
+		t.Error("Expected error for an empty key name")
+	}
+}
+
+func TestParseRename(t *testing.T) {
+	for _, s := range []string{"novalue", "=x", "(=x"} {
+		if _, err := importer.ParseRename(s); err == nil {
+			t.Errorf("ParseRename(%q) expected error", s)
+		}
+	}
+}
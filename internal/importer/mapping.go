@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+// Rename replaces key names matching a regular expression.
+type Rename struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// ParseRename parses a rename rule in pattern=replacement form, split at the last '='.
+// The replacement may refer to submatches as $1 or ${name}.
+func ParseRename(s string) (Rename, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return Rename{}, fmt.Errorf("invalid rename rule %q, expected pattern=replacement", s)
+	}
+	re, err := regexp.Compile(s[:i])
+	if err != nil {
+		return Rename{}, fmt.Errorf("invalid rename rule %q: %w", s, err)
+	}
+	return Rename{re: re, repl: s[i+1:]}, nil
+}
+
+// KeyMapping maps imported key names. Trim, rename rules and prefix are applied in that order.
+type KeyMapping struct {
+	// TrimPath keeps only the final path part of a key name (e.g., /foo/bar => bar).
+	TrimPath bool
+	Rename   []Rename
+	Prefix   string
+}
+
+// Key returns the mapped key name.
+func (m KeyMapping) Key(key string) string {
+	if m.TrimPath {
+		key = key[strings.LastIndex(key, "/")+1:]
+	}
+	for _, r := range m.Rename {
+		key = r.re.ReplaceAllString(key, r.repl)
+	}
+	return m.Prefix + key
+}
+
+// Apply maps key names of entities.
+// Keys that map to an empty name or to the same name as another key are an error.
+func (m KeyMapping) Apply(entities []models.Entity) ([]models.Entity, error) {
+	out := make([]models.Entity, 0, len(entities))
+	from := make(map[string]string, len(entities))
+	for _, e := range entities {
+		k := m.Key(e.Key)
+		if k == "" {
+			return nil, fmt.Errorf("key '%s' maps to an empty name", e.Key)
+		}
+		if prev, ok := from[k]; ok {
+			return nil, fmt.Errorf("keys '%s' and '%s' both map to '%s'", prev, e.Key, k)
+		}
+		from[k] = e.Key
+		e.Key = k
+		out = append(out, e)
+	}
+	return out, nil
+}
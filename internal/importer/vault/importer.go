@@ -0,0 +1,134 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+func init() {
+	importer.Register("vault", func() importer.Importer { return &Importer{} })
+}
+
+// Importer imports secrets from a HashiCorp Vault KV secrets engine.
+type Importer struct {
+	addr      string
+	token     string
+	namespace string
+	tls       TLSConfig
+	opts      Options
+	flags     *pflag.FlagSet
+}
+
+// Info describes the importer for the command line.
+func (i *Importer) Info() importer.Info {
+	return importer.Info{
+		Short: "Import secrets from HashiCorp Vault KV v1/v2",
+		Long: `Fetch secrets from a HashiCorp Vault KV secrets engine (v1 or v2) and store them in the local kv bucket.
+Every field of a secret is stored as a separate key named <secret path>/<field>.
+
+The secret at --path is imported together with the secrets directly under it, --recursive also walks subfolders.
+The engine version is detected from the mount unless --kv-version is set.
+The address, token and namespace default to VAULT_ADDR, VAULT_TOKEN (or ~/.vault-token) and VAULT_NAMESPACE.
+The server certificate is verified with the CA certificates from --ca-cert or --ca-path (VAULT_CACERT or VAULT_CAPATH),
+--tls-skip-verify (VAULT_SKIP_VERIFY) disables the verification.`,
+		Example: `
+  # Import all secrets under app/prod of the "secret" mount
+  # e.g., app/prod/db {"username":"admin"} => app/prod/db/username
+  kv import vault --bucket=prod --addr=https://vault:8200 --mount=secret --path=app/prod --recursive
+
+  # Import the fields of a single secret with their names only
+  # e.g., app/prod/db {"username":"admin"} => username
+  kv import vault --bucket=prod --path=app/prod/db --trim-key-name
+
+  # Import from a KV v1 engine when the token can't read the mount configuration
+  kv import vault --bucket=legacy --mount=kv --kv-version=1 --path=team
+`,
+	}
+}
+
+// Flags returns the Vault connection and path flags.
+func (i *Importer) Flags() *pflag.FlagSet {
+	if i.flags == nil {
+		i.flags = pflag.NewFlagSet("vault", pflag.ContinueOnError)
+		i.flags.StringVar(&i.addr, "addr", "", "Vault server address (can also use VAULT_ADDR)")
+		i.flags.StringVar(&i.token, "token", "", "Vault token (can also use VAULT_TOKEN or ~/.vault-token)")
+		i.flags.StringVar(&i.namespace, "namespace", "", "Vault namespace (can also use VAULT_NAMESPACE)")
+		i.flags.StringVar(&i.tls.CACert, "ca-cert", "", "PEM file with CA certificates to verify the server (can also use VAULT_CACERT)")
+		i.flags.StringVar(&i.tls.CAPath, "ca-path", "", "Directory with PEM files of CA certificates to verify the server (can also use VAULT_CAPATH)")
+		i.flags.BoolVar(&i.tls.SkipVerify, "tls-skip-verify", false, "Don't verify the server certificate, insecure (can also use VAULT_SKIP_VERIFY)")
+		i.flags.StringVar(&i.opts.Mount, "mount", "secret", "Mount path of the KV secrets engine")
+		i.flags.StringVar(&i.opts.Path, "path", "", "Secret or folder path inside the mount")
+		i.flags.IntVar(&i.opts.Version, "kv-version", 0, "KV secrets engine version [1, 2], detected from the mount by default")
+		i.flags.BoolVarP(&i.opts.Recursive, "recursive", "r", false, "Recursive import")
+	}
+	return i.flags
+}
+
+// Fetch returns the fields of secrets under the path.
+func (i *Importer) Fetch(ctx context.Context, args []string) ([]models.Entity, error) {
+	client, err := NewClient(orEnv(i.addr, "VAULT_ADDR"), i.vaultToken(), orEnv(i.namespace, "VAULT_NAMESPACE"))
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := i.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := client.ConfigureTLS(tlsConfig); err != nil {
+		return nil, err
+	}
+	secrets, err := GetSecrets(ctx, client, i.opts)
+	if err != nil {
+		return nil, err
+	}
+	return importer.FromMap(secrets), nil
+}
+
+// tlsConfig returns the TLS flags with the defaults from VAULT_CACERT, VAULT_CAPATH and VAULT_SKIP_VERIFY.
+func (i *Importer) tlsConfig() (TLSConfig, error) {
+	cfg := TLSConfig{
+		CACert:     orEnv(i.tls.CACert, "VAULT_CACERT"),
+		CAPath:     orEnv(i.tls.CAPath, "VAULT_CAPATH"),
+		SkipVerify: i.tls.SkipVerify,
+	}
+	if v := os.Getenv("VAULT_SKIP_VERIFY"); v != "" && !cfg.SkipVerify {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("vault: invalid VAULT_SKIP_VERIFY %q: %w", v, err)
+		}
+		cfg.SkipVerify = skip
+	}
+	return cfg, nil
+}
+
+// vaultToken returns the token from the flag, VAULT_TOKEN or the token file written by `vault login`.
+func (i *Importer) vaultToken() string {
+	if t := orEnv(i.token, "VAULT_TOKEN"); t != "" {
+		return t
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// orEnv returns v or the value of the environment variable if v is empty.
+func orEnv(v, env string) string {
+	if v != "" {
+		return v
+	}
+	return os.Getenv(env)
+}
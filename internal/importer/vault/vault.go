@@ -0,0 +1,318 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("vault: path not found")
+	ErrUnknownKVVersion  = errors.New("vault: unknown KV secrets engine version")
+	ErrAddressIsRequired = errors.New("vault: address is required (--addr or VAULT_ADDR)")
+)
+
+// Client is a minimal client of the Vault HTTP API for the KV secrets engine.
+type Client struct {
+	Addr      string
+	Token     string
+	Namespace string
+	HTTP      *http.Client
+}
+
+// NewClient creates a client for a Vault server address, e.g. https://vault:8200.
+func NewClient(addr, token, namespace string) (*Client, error) {
+	if addr == "" {
+		return nil, ErrAddressIsRequired
+	}
+	return &Client{
+		Addr:      strings.TrimRight(addr, "/"),
+		Token:     token,
+		Namespace: namespace,
+		HTTP:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// TLSConfig configures the verification of the server certificate, like the Vault CLI.
+type TLSConfig struct {
+	CACert     string // PEM file with CA certificates (VAULT_CACERT)
+	CAPath     string // directory with PEM files of CA certificates, ignored with CACert (VAULT_CAPATH)
+	SkipVerify bool   // don't verify the server certificate (VAULT_SKIP_VERIFY)
+}
+
+// ConfigureTLS sets the CA certificates used to verify the server instead of the system ones.
+func (c *Client) ConfigureTLS(cfg TLSConfig) error {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.SkipVerify}
+
+	var files []string
+	switch {
+	case cfg.CACert != "":
+		files = []string{cfg.CACert}
+	case cfg.CAPath != "":
+		entries, err := os.ReadDir(cfg.CAPath)
+		if err != nil {
+			return fmt.Errorf("vault: read CA path: %w", err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(cfg.CAPath, e.Name()))
+			}
+		}
+	}
+	if len(files) > 0 {
+		pool := x509.NewCertPool()
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("vault: read CA certificate: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return fmt.Errorf("vault: no PEM certificates in %s", f)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.HTTP.Transport = transport
+	return nil
+}
+
+// Options selects secrets to fetch.
+type Options struct {
+	Mount     string // mount path of the KV secrets engine, e.g. "secret"
+	Path      string // secret or folder path inside the mount
+	Version   int    // KV engine version 1 or 2, 0 detects it from the mount
+	Recursive bool   // also fetch secrets in subfolders
+}
+
+// GetSecrets fetches the secret at the path and the secrets directly under it,
+// or under all its subfolders when recursive. Every field of a secret is returned
+// as a separate key named "<secret path>/<field>". Field values that are not strings
+// are JSON encoded.
+func GetSecrets(ctx context.Context, c *Client, opts Options) (map[string]string, error) {
+	mount := strings.Trim(opts.Mount, "/")
+	path := strings.Trim(opts.Path, "/")
+
+	version := opts.Version
+	if version == 0 {
+		v, err := c.KVVersion(ctx, mount)
+		if err != nil {
+			return nil, err
+		}
+		version = v
+	}
+	if version != 1 && version != 2 {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKVVersion, version)
+	}
+
+	secrets := map[string]string{}
+	if path != "" {
+		if err := readSecret(ctx, c, mount, version, path, secrets); err != nil {
+			return nil, err
+		}
+	}
+
+	var walk func(folder string) error
+	walk = func(folder string) error {
+		keys, err := c.List(ctx, mount, version, folder)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			p := strings.TrimSuffix(joinPath(folder, k), "/")
+			if strings.HasSuffix(k, "/") {
+				if opts.Recursive {
+					if err := walk(p); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := readSecret(ctx, c, mount, version, p, secrets); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(path); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// readSecret adds the fields of a secret to secrets, a missing secret is ignored.
+func readSecret(ctx context.Context, c *Client, mount string, version int, path string, secrets map[string]string) error {
+	data, err := c.Read(ctx, mount, version, path)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for field, v := range data {
+		value, err := fieldValue(v)
+		if err != nil {
+			return fmt.Errorf("secret '%s' field '%s': %w", path, field, err)
+		}
+		secrets[path+"/"+field] = value
+	}
+	return nil
+}
+
+// KVVersion returns the version of the KV secrets engine at the mount.
+func (c *Client) KVVersion(ctx context.Context, mount string) (int, error) {
+	var resp struct {
+		Data struct {
+			Type    string            `json:"type"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}
+	if err := c.get(ctx, "sys/internal/ui/mounts/"+mount, nil, &resp); err != nil {
+		return 0, fmt.Errorf("detect KV version of mount '%s' (set it with --kv-version): %w", mount, err)
+	}
+	if resp.Data.Options["version"] == "2" {
+		return 2, nil
+	}
+	return 1, nil
+}
+
+// List returns the names of secrets and folders (with a trailing "/") in a folder.
+// A missing folder has no keys.
+func (c *Client) List(ctx context.Context, mount string, version int, folder string) ([]string, error) {
+	p := mount
+	if version == 2 {
+		p += "/metadata"
+	}
+	var resp struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err := c.get(ctx, joinPath(p, folder), url.Values{"list": {"true"}}, &resp)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.Keys, nil
+}
+
+// Read returns the fields of the latest version of a secret.
+func (c *Client) Read(ctx context.Context, mount string, version int, path string) (map[string]any, error) {
+	if version == 2 {
+		var resp struct {
+			Data struct {
+				Data map[string]any `json:"data"`
+			} `json:"data"`
+		}
+		if err := c.get(ctx, joinPath(mount+"/data", path), nil, &resp); err != nil {
+			return nil, err
+		}
+		// the latest version is deleted
+		if resp.Data.Data == nil {
+			return nil, ErrNotFound
+		}
+		return resp.Data.Data, nil
+	}
+
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := c.get(ctx, joinPath(mount, path), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// get sends a GET request to the API path and decodes the JSON response into out.
+// Every segment of the path is escaped, so secret names can't change the request.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	u := c.Addr + "/v1/" + escapePath(path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Vault-Token", c.Token)
+	}
+	if c.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(body, &e) == nil && len(e.Errors) > 0 {
+			return fmt.Errorf("vault: %s: %s", resp.Status, strings.Join(e.Errors, "; "))
+		}
+		return fmt.Errorf("vault: %s", resp.Status)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+// fieldValue converts a secret field to a string value.
+func fieldValue(v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// escapePath escapes every segment of an API path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// joinPath joins API path parts skipping empty ones.
+func joinPath(parts ...string) string {
+	var p []string
+	for _, s := range parts {
+		if s = strings.Trim(s, "/"); s != "" {
+			p = append(p, s)
+		}
+	}
+	return strings.Join(p, "/")
+}
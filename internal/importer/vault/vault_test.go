@@ -0,0 +1,326 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/importer"
+	"github.com/yousysadmin/kv/internal/importer/vault"
+)
+
+const testToken = "s.test"
+
+// newServer mimics the list and read endpoints of a Vault KV engine mounted at "secret".
+// secrets maps secret paths to their fields.
+func newServer(t *testing.T, version int, secrets map[string]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(vaultHandler(version, secrets))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// vaultHandler serves the endpoints of newServer.
+func vaultHandler(version int, secrets map[string]map[string]any) http.Handler {
+
+	write := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	notFound := func(w http.ResponseWriter) {
+		write(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != testToken {
+			write(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+
+		p := strings.TrimPrefix(r.URL.Path, "/v1/")
+		if p == "sys/internal/ui/mounts/secret" {
+			write(w, http.StatusOK, map[string]any{"data": map[string]any{
+				"type":    "kv",
+				"options": map[string]string{"version": map[int]string{1: "1", 2: "2"}[version]},
+			}})
+			return
+		}
+
+		p = strings.TrimPrefix(p, "secret/")
+		if version == 2 {
+			if r.URL.Query().Get("list") == "true" {
+				p = strings.TrimPrefix(p, "metadata")
+			} else {
+				p = strings.TrimPrefix(p, "data")
+			}
+		}
+		p = strings.Trim(p, "/")
+
+		if r.URL.Query().Get("list") == "true" {
+			seen := map[string]bool{}
+			var keys []string
+			for name := range secrets {
+				rest, ok := strings.CutPrefix(name, p+"/")
+				if p == "" {
+					rest, ok = name, true
+				}
+				if !ok {
+					continue
+				}
+				if i := strings.Index(rest, "/"); i >= 0 {
+					rest = rest[:i+1]
+				}
+				if !seen[rest] {
+					seen[rest] = true
+					keys = append(keys, rest)
+				}
+			}
+			if len(keys) == 0 {
+				notFound(w)
+				return
+			}
+			write(w, http.StatusOK, map[string]any{"data": map[string]any{"keys": keys}})
+			return
+		}
+
+		fields, ok := secrets[p]
+		if !ok {
+			notFound(w)
+			return
+		}
+		if version == 2 {
+			write(w, http.StatusOK, map[string]any{"data": map[string]any{"data": fields, "metadata": map[string]any{"version": 1}}})
+			return
+		}
+		write(w, http.StatusOK, map[string]any{"data": fields})
+	})
+}
+
+var testSecrets = map[string]map[string]any{
+	"app/prod":          {"region": "eu"},
+	"app/prod/db":       {"username": "admin", "port": 5432},
+	"app/prod/api/keys": {"token": "t0k"},
+	"app/stage/db":      {"username": "stage"},
+}
+
+func TestGetSecrets(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   int
+		recursive bool
+		want      map[string]string
+	}{
+		{
+			name:    "v2 folder",
+			version: 2,
+			want: map[string]string{
+				"app/prod/region":      "eu",
+				"app/prod/db/username": "admin",
+				"app/prod/db/port":     "5432",
+			},
+		},
+		{
+			name:      "v2 recursive",
+			version:   2,
+			recursive: true,
+			want: map[string]string{
+				"app/prod/region":         "eu",
+				"app/prod/db/username":    "admin",
+				"app/prod/db/port":        "5432",
+				"app/prod/api/keys/token": "t0k",
+			},
+		},
+		{
+			name:      "v1 recursive",
+			version:   1,
+			recursive: true,
+			want: map[string]string{
+				"app/prod/region":         "eu",
+				"app/prod/db/username":    "admin",
+				"app/prod/db/port":        "5432",
+				"app/prod/api/keys/token": "t0k",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, tt.version, testSecrets)
+			c, err := vault.NewClient(srv.URL, testToken, "")
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			got, err := vault.GetSecrets(context.Background(), c, vault.Options{
+				Mount:     "secret",
+				Path:      "app/prod/",
+				Recursive: tt.recursive,
+			})
+			if err != nil {
+				t.Fatalf("GetSecrets failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSecrets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretsSingle(t *testing.T) {
+	srv := newServer(t, 2, testSecrets)
+	c, _ := vault.NewClient(srv.URL, testToken, "")
+
+	got, err := vault.GetSecrets(context.Background(), c, vault.Options{Mount: "secret", Path: "app/stage/db", Version: 2})
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	want := map[string]string{"app/stage/db/username": "stage"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSecrets() = %v, want %v", got, want)
+	}
+}
+
+func TestGetSecretsErrors(t *testing.T) {
+	srv := newServer(t, 2, testSecrets)
+
+	c, _ := vault.NewClient(srv.URL, "wrong", "")
+	_, err := vault.GetSecrets(context.Background(), c, vault.Options{Mount: "secret", Path: "app", Version: 2})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("GetSecrets() error = %v, want permission denied", err)
+	}
+
+	c, _ = vault.NewClient(srv.URL, testToken, "")
+	if _, err := vault.GetSecrets(context.Background(), c, vault.Options{Mount: "secret", Version: 3}); err == nil {
+		t.Error("GetSecrets() with KV version 3 should fail")
+	}
+
+	if _, err := vault.NewClient("", testToken, ""); err == nil {
+		t.Error("NewClient() without address should fail")
+	}
+}
+
+func TestImporter(t *testing.T) {
+	srv := newServer(t, 2, testSecrets)
+
+	imp, err := importer.New("vault")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	err = imp.Flags().Parse([]string{"--addr", srv.URL, "--token", testToken, "--path", "app/stage", "-r"})
+	if err != nil {
+		t.Fatalf("Parse flags failed: %v", err)
+	}
+
+	entities, err := imp.Fetch(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entities) != 1 || entities[0].Key != "app/stage/db/username" || entities[0].Value != "stage" {
+		t.Errorf("Fetch() = %v", entities)
+	}
+}
+
+func TestGetSecretsEscapesPath(t *testing.T) {
+	secrets := map[string]map[string]any{
+		"app/a?b#c":  {"token": "escaped"},
+		"app/100%25": {"token": "percent"},
+	}
+	srv := newServer(t, 2, secrets)
+	c, _ := vault.NewClient(srv.URL, testToken, "")
+
+	got, err := vault.GetSecrets(context.Background(), c, vault.Options{Mount: "secret", Path: "app", Version: 2})
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	want := map[string]string{"app/a?b#c/token": "escaped", "app/100%25/token": "percent"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSecrets() = %v, want %v", got, want)
+	}
+}
+
+func TestConfigureTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(vaultHandler(2, testSecrets))
+	t.Cleanup(srv.Close)
+	opts := vault.Options{Mount: "secret", Path: "app/stage/db", Version: 2}
+
+	dir := t.TempDir()
+	caCert := filepath.Join(dir, "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caCert, pemCert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     vault.TLSConfig
+		wantErr bool
+	}{
+		{name: "system CAs", cfg: vault.TLSConfig{}, wantErr: true},
+		{name: "CA cert", cfg: vault.TLSConfig{CACert: caCert}},
+		{name: "CA path", cfg: vault.TLSConfig{CAPath: dir}},
+		{name: "skip verify", cfg: vault.TLSConfig{SkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := vault.NewClient(srv.URL, testToken, "")
+			if err := c.ConfigureTLS(tt.cfg); err != nil {
+				t.Fatalf("ConfigureTLS failed: %v", err)
+			}
+			_, err := vault.GetSecrets(context.Background(), c, opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	c, _ := vault.NewClient(srv.URL, testToken, "")
+	if err := c.ConfigureTLS(vault.TLSConfig{CACert: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("ConfigureTLS() with a missing CA cert should fail")
+	}
+}
+
+func TestImporterTLSEnv(t *testing.T) {
+	srv := httptest.NewTLSServer(vaultHandler(2, testSecrets))
+	t.Cleanup(srv.Close)
+
+	fetch := func() error {
+		imp, err := importer.New("vault")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if err := imp.Flags().Parse([]string{"--addr", srv.URL, "--token", testToken, "--path", "app/stage/db"}); err != nil {
+			t.Fatalf("Parse flags failed: %v", err)
+		}
+		_, err = imp.Fetch(context.Background(), nil)
+		return err
+	}
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caCert, pemCert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_CACERT", caCert)
+	if err := fetch(); err != nil {
+		t.Errorf("Fetch() with VAULT_CACERT failed: %v", err)
+	}
+
+	t.Setenv("VAULT_CACERT", "")
+	t.Setenv("VAULT_SKIP_VERIFY", "true")
+	if err := fetch(); err != nil {
+		t.Errorf("Fetch() with VAULT_SKIP_VERIFY failed: %v", err)
+	}
+
+	t.Setenv("VAULT_SKIP_VERIFY", "maybe")
+	if err := fetch(); err == nil {
+		t.Error("Fetch() with an invalid VAULT_SKIP_VERIFY should fail")
+	}
+}
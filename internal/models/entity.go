@@ -1,6 +1,49 @@
 package models
 
+import "time"
+
 type Entity struct {
-	Key   string `json:"key" yaml:"key"`
-	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	Key      string `json:"key" yaml:"key"`
+	Value    string `json:"value,omitempty" yaml:"value,omitempty"`
+	Metadata `yaml:",inline"`
+}
+
+// Metadata describes a stored key value.
+// Keys written before metadata was introduced have empty metadata.
+type Metadata struct {
+	CreatedAt   time.Time         `json:"created_at,omitzero" yaml:"created_at,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at,omitzero" yaml:"updated_at,omitempty"`
+	UpdatedBy   string            `json:"updated_by,omitempty" yaml:"updated_by,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at,omitzero" yaml:"expires_at,omitempty"`
+}
+
+// Expired reports whether the value has an expiry time and it has passed at now.
+func (m Metadata) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// ExpiresWithin reports whether the value has an expiry time within d from now.
+// Already expired values are reported as well.
+func (m Metadata) ExpiresWithin(d time.Duration, now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && m.ExpiresAt.Before(now.Add(d))
+}
+
+// MatchTags reports whether metadata has all tags from filter.
+// An empty filter value matches any value of the tag.
+func (m Metadata) MatchTags(filter map[string]string) bool {
+	for k, v := range filter {
+		tv, ok := m.Tags[k]
+		if !ok || (v != "" && tv != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Version describes a stored revision of a key value.
+type Version struct {
+	Version   uint64    `json:"version" yaml:"version"`
+	CreatedAt time.Time `json:"created_at,omitzero" yaml:"created_at,omitempty"`
 }
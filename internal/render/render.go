@@ -0,0 +1,93 @@
+// Package render evaluates text/template templates with functions that read keys from kv.
+//
+// Template functions:
+//
+//	kv "key@bucket"        value of a key, "key" reads from the default bucket of the Source
+//	bucket "prod"          map of all key values in a bucket
+//	dotenv (bucket "prod") dotenv lines for a map of values, keys are normalized
+//	b64enc "value"         base64 encoded value
+//	default "x" .Value     .Value or "x" when .Value is empty
+//
+// A missing key is an error, so a template never renders with an empty secret by mistake.
+// For optional keys, look them up in a bucket map: {{ index (bucket "prod") "port" | default "8080" }}.
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"text/template"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+// Source resolves keys for template functions.
+type Source interface {
+	// Get returns the value of a key in "key" or "key@bucket" form.
+	Get(ref string) (string, error)
+	// Bucket returns all key values in a bucket.
+	Bucket(bucket string) (map[string]string, error)
+}
+
+// Funcs returns the template functions backed by src.
+func Funcs(src Source) template.FuncMap {
+	return template.FuncMap{
+		"kv":      src.Get,
+		"bucket":  src.Bucket,
+		"dotenv":  dotenv,
+		"b64enc":  b64enc,
+		"default": defaultValue,
+	}
+}
+
+// Render parses text as a template and executes it with functions backed by src.
+func Render(name string, text string, src Source) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(Funcs(src)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, fmt.Errorf("execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// dotenv formats key values as dotenv lines.
+func dotenv(values map[string]string) (string, error) {
+	entities := make([]models.Entity, 0, len(values))
+	for k, v := range values {
+		entities = append(entities, models.Entity{Key: k, Value: v})
+	}
+	return utils.ToDotenv(entities, true)
+}
+
+// b64enc returns the standard base64 encoding of s.
+func b64enc(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// defaultValue returns value, or def when value is missing or empty.
+// The argument order allows piping: {{ .Value | default "x" }}.
+func defaultValue(def any, value ...any) any {
+	if len(value) == 0 || isEmpty(value[0]) {
+		return def
+	}
+	return value[0]
+}
+
+// isEmpty reports whether v is nil or the zero value of its type,
+// empty maps and slices are empty as well.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
@@ -0,0 +1,78 @@
+package render_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/render"
+)
+
+// mapSource is a Source over static buckets.
+type mapSource map[string]map[string]string
+
+func (m mapSource) Get(ref string) (string, error) {
+	key, bucket, ok := strings.Cut(ref, "@")
+	if !ok {
+		bucket = "default"
+	}
+	v, ok := m[bucket][key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", ref)
+	}
+	return v, nil
+}
+
+func (m mapSource) Bucket(bucket string) (map[string]string, error) {
+	b, ok := m[bucket]
+	if !ok {
+		return nil, fmt.Errorf("bucket %s not found", bucket)
+	}
+	return b, nil
+}
+
+var src = mapSource{
+	"default": {"user": "admin"},
+	"prod":    {"db-host": "db.local", "db-pass": `p"w`, "empty": ""},
+}
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"kv", `{{ kv "user" }}:{{ kv "db-host@prod" }}`, "admin:db.local"},
+		{"bucket", `{{ with bucket "prod" }}{{ index . "db-host" }}{{ end }}`, "db.local"},
+		{"range", `{{ range $k, $v := bucket "prod" }}{{ $k }};{{ end }}`, "db-host;db-pass;empty;"},
+		{"dotenv", `{{ bucket "prod" | dotenv }}`, "DB_HOST=\"db.local\"\nDB_PASS=\"p\\\"w\"\nEMPTY=\n"},
+		{"b64enc", `{{ kv "user" | b64enc }}`, "YWRtaW4="},
+		{"default empty", `{{ index (bucket "prod") "empty" | default "x" }}`, "x"},
+		{"default missing", `{{ index (bucket "prod") "port" | default "8080" }}`, "8080"},
+		{"default set", `{{ kv "user" | default "x" }}`, "admin"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := render.Render(c.name, c.tmpl, src)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if string(got) != c.want {
+				t.Errorf("Render() = %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		`{{ kv "missing@prod" }}`,
+		`{{ bucket "missing" }}`,
+		`{{ kv "user" `,
+		`{{ unknown }}`,
+	} {
+		if _, err := render.Render("t", tmpl, src); err == nil {
+			t.Errorf("Render(%q) expected error", tmpl)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Package server implements a local HTTP/JSON API over the kv store.
+
+The server holds a single long-lived database handle and the loaded encryption keys,
+so clients don't pay process startup and key store loading on every lookup.
+
+# Endpoints
+
+	GET    /health                              server health, doesn't require a token
+	GET    /v1/buckets                          list bucket names
+	GET    /v1/buckets/{bucket}/keys            list keys with metadata (?values=true, ?tag=k=v)
+	GET    /v1/buckets/{bucket}/keys/{key}      get a key value with metadata (?version=N)
+	PUT    /v1/buckets/{bucket}/keys/{key}      add or update a key, body: {"value": "...", "description": "...", "tags": {...}, "expires_at": "..."}
+	DELETE /v1/buckets/{bucket}/keys/{key}      delete a key and its history
+
+Key names may contain "/", a leading "/" must be escaped as %2F.
+
+Errors are returned as {"error": "..."} with a matching status code:
+404 for a missing bucket, key or version, 410 for an expired key,
+400 for an invalid request and 401 for a missing or wrong token.
+
+# Authentication
+
+When a token is set, every request except /health must have an
+"Authorization: Bearer <token>" header.
+
+# Listen address
+
+	unix:///run/user/1000/kv.sock   unix socket, created with 0600 permissions
+	tcp://127.0.0.1:8200            TCP
+	127.0.0.1:8200                  TCP
+*/
+package server
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Listen creates a listener for an address in unix:///path, tcp://host:port or host:port form.
+// A unix socket is created with 0600 permissions, a stale socket file is removed first.
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		if path == "" {
+			return nil, fmt.Errorf("invalid listen address %q: empty socket path", addr)
+		}
+		if err := removeStaleSocket(path); err != nil {
+			return nil, err
+		}
+		return listenUnix(path)
+	}
+	return net.Listen("tcp", strings.TrimPrefix(addr, "tcp://"))
+}
+
+// unixListener removes the socket file on Close.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Close removes the socket file and stops listening. The file is removed first,
+// a process that exits when Accept fails may not get to it otherwise.
+func (l *unixListener) Close() error {
+	_ = os.Remove(l.path)
+	return l.UnixListener.Close()
+}
+
+// listenUnix binds the socket in a private 0700 directory next to path, sets 0600 permissions
+// and moves it to path. The socket is never reachable by other users with the umask permissions
+// it is created with.
+func listenUnix(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".kv")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	ul := l.(*net.UnixListener)
+	// the socket file is moved, unixListener removes it on Close
+	ul.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ul.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ul.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: ul, path: path}, nil
+}
+
+// IsUnix reports whether the address is a unix socket address.
+func IsUnix(addr string) bool {
+	return strings.HasPrefix(addr, "unix://")
+}
+
+// removeStaleSocket removes a socket file left by a server that wasn't stopped cleanly.
+// Other files and sockets with a running server are kept.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
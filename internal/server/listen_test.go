@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/server"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kv")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kv.sock")
+
+	l, err := server.Listen("unix://" + path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("socket permissions = %v; want 0600", fi.Mode().Perm())
+	}
+
+	// the socket is bound in a temporary directory that is removed
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the socket in %s, got %v", dir, entries)
+	}
+
+	// a socket in use can't be taken over
+	if _, err := server.Listen("unix://" + path); err == nil {
+		t.Error("Expected error for a socket in use")
+	}
+	l.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket to be removed on Close, got: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Listen("unix://" + filepath.Join(dir, "file")); err == nil {
+		t.Error("Expected error for a regular file")
+	}
+}
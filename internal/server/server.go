@@ -0,0 +1,209 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/internal/utils"
+	"go.etcd.io/bbolt"
+	bboltErr "go.etcd.io/bbolt/errors"
+)
+
+// maxBodySize limits the size of a request body.
+const maxBodySize = 1 << 20
+
+// KeyFunc returns the encryption key for a bucket.
+type KeyFunc func(bucket string) (string, error)
+
+// Server serves the kv HTTP API.
+type Server struct {
+	db    *bbolt.DB
+	keyFn KeyFunc
+	token string
+}
+
+// New creates a new Server. An empty token disables authentication.
+func New(db *bbolt.DB, keyFn KeyFunc, token string) *Server {
+	return &Server{db: db, keyFn: keyFn, token: token}
+}
+
+// Handler returns the HTTP handler with all API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.health)
+	mux.Handle("GET /v1/buckets", s.auth(s.listBuckets))
+	mux.Handle("GET /v1/buckets/{bucket}/keys", s.auth(s.listKeys))
+	mux.Handle("GET /v1/buckets/{bucket}/keys/{key...}", s.auth(s.getKey))
+	mux.Handle("PUT /v1/buckets/{bucket}/keys/{key...}", s.auth(s.putKey))
+	mux.Handle("DELETE /v1/buckets/{bucket}/keys/{key...}", s.auth(s.deleteKey))
+	return mux
+}
+
+// auth checks the bearer token of a request.
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) listBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets, err := storage.NewEntityStorage(s.db, "").ListBuckets()
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if buckets == nil {
+		buckets = []string{}
+	}
+	writeJSON(w, http.StatusOK, buckets)
+}
+
+func (s *Server) listKeys(w http.ResponseWriter, r *http.Request) {
+	bucket := r.PathValue("bucket")
+	withValues, _ := strconv.ParseBool(r.URL.Query().Get("values"))
+	filter, err := utils.ParseTags(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	st, err := s.storage(bucket)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items, err := st.List(bucket, storage.ListOptions{Values: withValues, SkipExpired: withValues})
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	entities := []models.Entity{}
+	for _, e := range items {
+		if e.MatchTags(filter) {
+			entities = append(entities, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, entities)
+}
+
+func (s *Server) getKey(w http.ResponseWriter, r *http.Request) {
+	bucket, key := r.PathValue("bucket"), r.PathValue("key")
+	st, err := s.storage(bucket)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || version == 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid version %q", v))
+			return
+		}
+		value, err := st.GetVersion(bucket, key, version)
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, models.Entity{Key: key, Value: value})
+		return
+	}
+
+	e, err := st.GetEntity(bucket, key)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) putKey(w http.ResponseWriter, r *http.Request) {
+	bucket, key := r.PathValue("bucket"), r.PathValue("key")
+
+	var e models.Entity
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := dec.Decode(&e); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decode request: %w", err))
+		return
+	}
+	if e.Value == "" {
+		writeError(w, http.StatusBadRequest, storage.ErrValueIsEmpty)
+		return
+	}
+
+	st, err := s.storage(bucket)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// timestamps and writer are set by the storage
+	meta := models.Metadata{Description: e.Description, Tags: e.Tags, ExpiresAt: e.ExpiresAt}
+	if err := st.AddWithMetadata(bucket, key, e.Value, meta); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteKey(w http.ResponseWriter, r *http.Request) {
+	bucket, key := r.PathValue("bucket"), r.PathValue("key")
+	if err := storage.NewEntityStorage(s.db, "").Delete(bucket, key); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// storage returns an EntityStorage with the bucket encryption key.
+func (s *Server) storage(bucket string) (*storage.EntityStorage, error) {
+	k, err := s.keyFn(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewEntityStorage(s.db, k), nil
+}
+
+// writeStorageError writes a storage error with a matching status code.
+func writeStorageError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, bboltErr.ErrBucketNotFound),
+		errors.Is(err, storage.ErrValueIsEmpty),
+		errors.Is(err, storage.ErrVersionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, storage.ErrExpired):
+		status = http.StatusGone
+	case errors.Is(err, storage.ErrInvalidKeyName):
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
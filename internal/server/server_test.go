@@ -0,0 +1,232 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/server"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+const testToken = "secret-token"
+
+func setupTestServer(t *testing.T, token string) *httptest.Server {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open test DB: %v", err)
+	}
+	key, err := encrypt.GenerateRandomAESKey(encrypt.AES256)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyFn := func(bucket string) (string, error) { return key, nil }
+
+	ts := httptest.NewServer(server.New(db, keyFn, token).Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		db.Close()
+	})
+	return ts
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestPutGetDelete(t *testing.T) {
+	ts := setupTestServer(t, testToken)
+
+	code, body := do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/token", `{"value":"abc","description":"api token","tags":{"team":"payments"}}`)
+	if code != http.StatusNoContent {
+		t.Fatalf("PUT = %d %s", code, body)
+	}
+
+	code, body = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/token", "")
+	if code != http.StatusOK {
+		t.Fatalf("GET = %d %s", code, body)
+	}
+	var e models.Entity
+	if err := json.Unmarshal([]byte(body), &e); err != nil {
+		t.Fatalf("decode entity: %v", err)
+	}
+	if e.Key != "token" || e.Value != "abc" || e.Description != "api token" || e.Tags["team"] != "payments" {
+		t.Errorf("unexpected entity: %+v", e)
+	}
+
+	code, _ = do(t, ts, http.MethodDelete, "/v1/buckets/prod/keys/token", "")
+	if code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", code)
+	}
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/token", ""); code != http.StatusNotFound {
+		t.Errorf("GET deleted key = %d; want 404", code)
+	}
+}
+
+func TestKeyWithSlashes(t *testing.T) {
+	ts := setupTestServer(t, testToken)
+
+	if code, body := do(t, ts, http.MethodPut, "/v1/buckets/ssm/keys/%2Fprod%2Fdb_password", `{"value":"pw"}`); code != http.StatusNoContent {
+		t.Fatalf("PUT = %d %s", code, body)
+	}
+	if code, body := do(t, ts, http.MethodPut, "/v1/buckets/ssm/keys/app/db_user", `{"value":"user"}`); code != http.StatusNoContent {
+		t.Fatalf("PUT = %d %s", code, body)
+	}
+
+	code, body := do(t, ts, http.MethodGet, "/v1/buckets/ssm/keys?values=true", "")
+	if code != http.StatusOK {
+		t.Fatalf("list = %d %s", code, body)
+	}
+	var items []models.Entity
+	_ = json.Unmarshal([]byte(body), &items)
+	got := map[string]string{}
+	for _, e := range items {
+		got[e.Key] = e.Value
+	}
+	if got["/prod/db_password"] != "pw" || got["app/db_user"] != "user" {
+		t.Errorf("unexpected keys: %v", got)
+	}
+}
+
+func TestListBucketsAndKeys(t *testing.T) {
+	ts := setupTestServer(t, testToken)
+
+	do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/a", `{"value":"1","tags":{"team":"payments"}}`)
+	do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/b", `{"value":"2"}`)
+	do(t, ts, http.MethodPut, "/v1/buckets/stage/keys/c", `{"value":"3"}`)
+
+	code, body := do(t, ts, http.MethodGet, "/v1/buckets", "")
+	var buckets []string
+	_ = json.Unmarshal([]byte(body), &buckets)
+	if code != http.StatusOK || len(buckets) != 2 {
+		t.Errorf("list buckets = %d %s", code, body)
+	}
+
+	code, body = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys?tag=team=payments", "")
+	var items []models.Entity
+	_ = json.Unmarshal([]byte(body), &items)
+	if code != http.StatusOK || len(items) != 1 || items[0].Key != "a" || items[0].Value != "" {
+		t.Errorf("list keys by tag = %d %s", code, body)
+	}
+
+	// tags are parsed like on the command line
+	code, body = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys?tag=team%20=%20payments", "")
+	items = nil
+	_ = json.Unmarshal([]byte(body), &items)
+	if code != http.StatusOK || len(items) != 1 || items[0].Key != "a" {
+		t.Errorf("list keys by padded tag = %d %s", code, body)
+	}
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys?tag=%20=payments", ""); code != http.StatusBadRequest {
+		t.Errorf("list keys by invalid tag = %d; want 400", code)
+	}
+
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/missing/keys", ""); code != http.StatusNotFound {
+		t.Errorf("list missing bucket = %d; want 404", code)
+	}
+}
+
+func TestGetVersionAndExpired(t *testing.T) {
+	ts := setupTestServer(t, testToken)
+
+	do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/k", `{"value":"v1"}`)
+	do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/k", `{"value":"v2"}`)
+
+	code, body := do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/k?version=1", "")
+	if code != http.StatusOK || !strings.Contains(body, `"value":"v1"`) {
+		t.Errorf("GET version 1 = %d %s", code, body)
+	}
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/k?version=9", ""); code != http.StatusNotFound {
+		t.Errorf("GET missing version = %d; want 404", code)
+	}
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/k?version=x", ""); code != http.StatusBadRequest {
+		t.Errorf("GET invalid version = %d; want 400", code)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/old", `{"value":"x","expires_at":"`+past+`"}`)
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/old", ""); code != http.StatusGone {
+		t.Errorf("GET expired key = %d; want 410", code)
+	}
+	if code, _ = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys/old?version=1", ""); code != http.StatusGone {
+		t.Errorf("GET version of expired key = %d; want 410", code)
+	}
+	if code, body = do(t, ts, http.MethodGet, "/v1/buckets/prod/keys?values=true", ""); code != http.StatusOK || strings.Contains(body, `"old"`) {
+		t.Errorf("GET keys with values = %d %s; want the expired key left out", code, body)
+	}
+}
+
+func TestInvalidPut(t *testing.T) {
+	ts := setupTestServer(t, testToken)
+
+	for _, body := range []string{`not json`, `{"value":""}`} {
+		if code, _ := do(t, ts, http.MethodPut, "/v1/buckets/prod/keys/k", body); code != http.StatusBadRequest {
+			t.Errorf("PUT %q = %d; want 400", body, code)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	ts := setupTestServer(t, testToken)
+
+	resp, err := ts.Client().Get(ts.URL + "/v1/buckets")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET without token = %d; want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/buckets", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET with wrong token = %d; want 401", resp.StatusCode)
+	}
+
+	// health doesn't require a token
+	resp, err = ts.Client().Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /health = %d; want 200", resp.StatusCode)
+	}
+}
+
+func TestNoToken(t *testing.T) {
+	ts := setupTestServer(t, "")
+
+	resp, err := ts.Client().Get(ts.URL + "/v1/buckets")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET without token = %d; want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"go.etcd.io/bbolt"
+)
+
+// ConflictPolicy defines what AddBatch does with a key that already exists with another value.
+type ConflictPolicy string
+
+const (
+	ConflictOverwrite ConflictPolicy = "overwrite" // store the new value as the next version
+	ConflictSkip      ConflictPolicy = "skip"      // keep the existing value
+	ConflictFail      ConflictPolicy = "fail"      // abort the whole batch
+	ConflictRename    ConflictPolicy = "rename"    // store the new value under <key>.<n>
+)
+
+var (
+	ErrConflict              = errors.New("key already exists with another value")
+	ErrUnknownConflictPolicy = errors.New("unknown conflict policy, expected skip, overwrite, fail or rename")
+)
+
+// ParseConflictPolicy validates a conflict policy name.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictOverwrite, ConflictSkip, ConflictFail, ConflictRename:
+		return p, nil
+	}
+	return "", ErrUnknownConflictPolicy
+}
+
+// BatchAction is what AddBatch does with an entity.
+type BatchAction string
+
+const (
+	BatchCreated   BatchAction = "created"
+	BatchUpdated   BatchAction = "updated"
+	BatchSkipped   BatchAction = "skipped"
+	BatchUnchanged BatchAction = "unchanged"
+	BatchDeleted   BatchAction = "deleted"
+)
+
+// BatchItem is the result of AddBatch for an entity.
+type BatchItem struct {
+	Key    string      `json:"key"`
+	Action BatchAction `json:"action"`
+	// StoredAs is the key name the value is stored under, it differs from Key when renamed.
+	StoredAs string `json:"stored_as"`
+}
+
+// BatchOptions sets how AddBatch stores entities.
+type BatchOptions struct {
+	OnConflict ConflictPolicy
+	// Delete are keys deleted with their history in the same transaction,
+	// after the entities are stored. Keys that don't exist are skipped.
+	Delete []string
+	// DryRun returns the results without writing.
+	DryRun bool
+}
+
+// AddBatch inserts and encrypts entities with their metadata into the specified bucket
+// in a single transaction, either all entities are stored or none is.
+// A key with the same value is left unchanged, a key with another value is handled by the conflict policy.
+// A renamed value gets the first <key>.<n> name that is neither stored nor a key of the batch.
+// A stored value that can't be decrypted with the storage key fails the whole batch.
+// It returns a result for every entity in the input order, followed by the deleted keys.
+func (d *EntityStorage) AddBatch(bucket string, entities []models.Entity, opts BatchOptions) ([]BatchItem, error) {
+	policy := opts.OnConflict
+	if policy == "" {
+		policy = ConflictOverwrite
+	}
+
+	var items []BatchItem
+	fn := func(tx *bbolt.Tx) error {
+		items = items[:0]
+		b := tx.Bucket([]byte(bucket))
+		if b == nil && !opts.DryRun {
+			var err error
+			if b, err = tx.CreateBucket([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+
+		// keys of the batch, a renamed value must not clash with them
+		taken := make(map[string]bool, len(entities))
+		for _, e := range entities {
+			taken[e.Key] = true
+		}
+		// values stored by this batch, they are looked up here
+		// since a dry run doesn't write them to the bucket
+		stored := make(map[string]string, len(entities))
+		exists := func(key string) bool {
+			_, ok := stored[key]
+			return ok || taken[key] || (b != nil && b.Get([]byte(key)) != nil)
+		}
+
+		for _, e := range entities {
+			if err := ValidateKeyName(e.Key); err != nil {
+				return err
+			}
+			item := BatchItem{Key: e.Key, Action: BatchCreated, StoredAs: e.Key}
+
+			var found, same bool
+			if v, ok := stored[e.Key]; ok {
+				found, same = true, v == e.Value
+			} else if b != nil {
+				if cur := b.Get([]byte(e.Key)); cur != nil {
+					var err error
+					if same, err = d.sameValue(tx, bucket, e.Key, cur, e.Value); err != nil {
+						return fmt.Errorf("key '%s': %w", e.Key, err)
+					}
+					found = true
+				}
+			}
+			if found {
+				item.Action = BatchUpdated
+				switch {
+				case same:
+					item.Action = BatchUnchanged
+				case policy == ConflictSkip:
+					item.Action = BatchSkipped
+				case policy == ConflictFail:
+					return fmt.Errorf("key '%s': %w", e.Key, ErrConflict)
+				case policy == ConflictRename:
+					item.Action = BatchCreated
+					item.StoredAs = freeKey(e.Key, exists)
+				}
+			}
+			items = append(items, item)
+
+			if item.Action != BatchCreated && item.Action != BatchUpdated {
+				continue
+			}
+			stored[item.StoredAs] = e.Value
+			if opts.DryRun {
+				continue
+			}
+			encValue, err := d.cipher.Seal(bucket, item.StoredAs, e.Value)
+			if err != nil {
+				return fmt.Errorf("key '%s': %w", e.Key, err)
+			}
+			if err := putValue(b, item.StoredAs, encValue, e.Metadata); err != nil {
+				return fmt.Errorf("key '%s': %w", e.Key, err)
+			}
+		}
+
+		for _, key := range opts.Delete {
+			if b == nil || b.Get([]byte(key)) == nil {
+				continue
+			}
+			items = append(items, BatchItem{Key: key, Action: BatchDeleted, StoredAs: key})
+			if opts.DryRun {
+				continue
+			}
+			if err := deleteHistory(b, key); err != nil {
+				return fmt.Errorf("key '%s': %w", key, err)
+			}
+			if err := b.Delete([]byte(key)); err != nil {
+				return fmt.Errorf("key '%s': %w", key, err)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if opts.DryRun {
+		err = d.db.View(fn)
+	} else {
+		err = d.db.Update(fn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// sameValue reports whether a stored record has the value.
+// A value that can't be decrypted with the storage key returns the decryption error,
+// so a batch with a wrong key never overwrites it. An expired record is different,
+// so storing the value again clears the expiry.
+func (d *EntityStorage) sameValue(tx *bbolt.Tx, bucket string, key string, data []byte, value string) (bool, error) {
+	r, err := decodeRecord(data)
+	if err != nil {
+		return false, err
+	}
+	if r.Expired(time.Now()) {
+		return false, nil
+	}
+	cur, err := d.open(tx, bucket, key, r.Value)
+	if err != nil {
+		return false, fmt.Errorf("decrypt stored value: %w", err)
+	}
+	return cur == value, nil
+}
+
+// freeKey returns the first <key>.<n> name that doesn't exist.
+func freeKey(key string, exists func(string) bool) string {
+	for n := 1; ; n++ {
+		k := key + "." + strconv.Itoa(n)
+		if !exists(k) {
+			return k
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package storage_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func batchActions(items []storage.BatchItem) map[string]string {
+	out := make(map[string]string, len(items))
+	for _, it := range items {
+		out[it.Key] = string(it.Action) + ":" + it.StoredAs
+	}
+	return out
+}
+
+func setupBatch(t *testing.T) (*storage.EntityStorage, []models.Entity, func()) {
+	db, cleanup := setupTestDB(t)
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.Add("imp", "same", "1")
+	_ = s.Add("imp", "changed", "old")
+	_ = s.Add("imp", "changed.1", "taken")
+
+	entities := []models.Entity{
+		{Key: "same", Value: "1"},
+		{Key: "changed", Value: "new"},
+		{Key: "added", Value: "2", Metadata: models.Metadata{Description: "imported"}},
+	}
+	return s, entities, cleanup
+}
+
+func TestAddBatchPolicies(t *testing.T) {
+	cases := []struct {
+		policy  storage.ConflictPolicy
+		want    map[string]string
+		changed string
+	}{
+		{storage.ConflictOverwrite, map[string]string{"same": "unchanged:same", "changed": "updated:changed", "added": "created:added", "changed.2": "created:changed.2"}, "new"},
+		{storage.ConflictSkip, map[string]string{"same": "unchanged:same", "changed": "skipped:changed", "added": "created:added", "changed.2": "created:changed.2"}, "old"},
+		{storage.ConflictRename, map[string]string{"same": "unchanged:same", "changed": "created:changed.3", "added": "created:added", "changed.2": "created:changed.2"}, "old"},
+	}
+	for _, c := range cases {
+		t.Run(string(c.policy), func(t *testing.T) {
+			s, entities, cleanup := setupBatch(t)
+			defer cleanup()
+
+			// a renamed key must not take the name of a key later in the batch
+			entities = append(entities, models.Entity{Key: "changed.2", Value: "incoming"})
+
+			// the dry run reports the same results as the real run
+			for _, dryRun := range []bool{true, false} {
+				items, err := s.AddBatch("imp", entities, storage.BatchOptions{OnConflict: c.policy, DryRun: dryRun})
+				if err != nil {
+					t.Fatalf("AddBatch(dry run: %t) failed: %v", dryRun, err)
+				}
+				if got := batchActions(items); !reflect.DeepEqual(got, c.want) {
+					t.Errorf("AddBatch(dry run: %t) = %v; want %v", dryRun, got, c.want)
+				}
+			}
+			if v, _ := s.Get("imp", "changed"); v != c.changed {
+				t.Errorf("changed = %s; want %s", v, c.changed)
+			}
+			e, err := s.GetEntity("imp", "added")
+			if err != nil || e.Value != "2" || e.Description != "imported" {
+				t.Errorf("added = %+v, err: %v", e, err)
+			}
+			if c.policy == storage.ConflictRename {
+				if v, _ := s.Get("imp", "changed.3"); v != "new" {
+					t.Errorf("changed.3 = %s; want new", v)
+				}
+			}
+			if v, _ := s.Get("imp", "changed.2"); v != "incoming" {
+				t.Errorf("changed.2 = %s; want incoming", v)
+			}
+			// unchanged keys don't get a new version
+			if h, _ := s.History("imp", "same"); len(h) != 1 {
+				t.Errorf("unchanged key has %d versions", len(h))
+			}
+		})
+	}
+}
+
+func TestAddBatchFailIsAtomic(t *testing.T) {
+	s, entities, cleanup := setupBatch(t)
+	defer cleanup()
+
+	// the conflict comes after a new key, nothing must be written
+	entities = append([]models.Entity{{Key: "first", Value: "x"}}, entities...)
+	_, err := s.AddBatch("imp", entities, storage.BatchOptions{OnConflict: storage.ConflictFail})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got: %v", err)
+	}
+	if _, err := s.Get("imp", "first"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("Expected 'first' not to be stored, got: %v", err)
+	}
+}
+
+func TestAddBatchDryRun(t *testing.T) {
+	s, entities, cleanup := setupBatch(t)
+	defer cleanup()
+
+	items, err := s.AddBatch("imp", entities, storage.BatchOptions{OnConflict: storage.ConflictOverwrite, DryRun: true})
+	if err != nil {
+		t.Fatalf("AddBatch failed: %v", err)
+	}
+	want := map[string]string{"same": "unchanged:same", "changed": "updated:changed", "added": "created:added"}
+	if got := batchActions(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("AddBatch() = %v; want %v", got, want)
+	}
+	if v, _ := s.Get("imp", "changed"); v != "old" {
+		t.Errorf("dry run changed the value: %s", v)
+	}
+
+	// a missing bucket is not created on dry run
+	if _, err := s.AddBatch("new", entities, storage.BatchOptions{DryRun: true}); err != nil {
+		t.Fatalf("AddBatch failed: %v", err)
+	}
+	if ok, _ := s.BucketExist("new"); ok {
+		t.Error("dry run created a bucket")
+	}
+}
+
+func TestAddBatchDelete(t *testing.T) {
+	s, entities, cleanup := setupBatch(t)
+	defer cleanup()
+
+	items, err := s.AddBatch("imp", entities, storage.BatchOptions{Delete: []string{"changed.1", "missing"}})
+	if err != nil {
+		t.Fatalf("AddBatch failed: %v", err)
+	}
+	want := map[string]string{"same": "unchanged:same", "changed": "updated:changed", "added": "created:added", "changed.1": "deleted:changed.1"}
+	if got := batchActions(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("AddBatch() = %v; want %v", got, want)
+	}
+	if _, err := s.Get("imp", "changed.1"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("Expected 'changed.1' to be deleted, got: %v", err)
+	}
+	if _, err := s.History("imp", "changed.1"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("Expected 'changed.1' history to be deleted, got: %v", err)
+	}
+
+	// a failed batch doesn't delete anything
+	_, err = s.AddBatch("imp", []models.Entity{{Key: "changed", Value: "other"}}, storage.BatchOptions{OnConflict: storage.ConflictFail, Delete: []string{"same"}})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got: %v", err)
+	}
+	if v, _ := s.Get("imp", "same"); v != "1" {
+		t.Errorf("failed batch deleted 'same': %s", v)
+	}
+}
+
+func TestAddBatchExpired(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.AddWithMetadata("imp", "token", "1", models.Metadata{ExpiresAt: time.Now().Add(-time.Hour)})
+
+	// the same value of an expired key is stored again and the expiry is cleared
+	items, err := s.AddBatch("imp", []models.Entity{{Key: "token", Value: "1"}}, storage.BatchOptions{})
+	if err != nil {
+		t.Fatalf("AddBatch failed: %v", err)
+	}
+	if items[0].Action != storage.BatchUpdated {
+		t.Errorf("Expected updated, got %s", items[0].Action)
+	}
+	if v, err := s.Get("imp", "token"); err != nil || v != "1" {
+		t.Errorf("Get = '%s', err: %v", v, err)
+	}
+}
+
+func TestAddBatchUndecryptable(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_ = storage.NewEntityStorage(db, mustGenKey(t)).Add("imp", "token", "1")
+
+	// a batch with another key must not overwrite a value it can't decrypt
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	for _, policy := range []storage.ConflictPolicy{storage.ConflictOverwrite, storage.ConflictSkip, storage.ConflictRename} {
+		_, err := s.AddBatch("imp", []models.Entity{{Key: "token", Value: "2"}, {Key: "new", Value: "x"}}, storage.BatchOptions{OnConflict: policy})
+		if err == nil {
+			t.Errorf("%s: expected a decryption error", policy)
+		}
+	}
+	if _, err := s.Get("imp", "new"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("Expected 'new' not to be stored, got: %v", err)
+	}
+	if h, _ := s.History("imp", "token"); len(h) != 1 {
+		t.Errorf("Expected 'token' to keep a single version, got %d", len(h))
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	if p, err := storage.ParseConflictPolicy("skip"); err != nil || p != storage.ConflictSkip {
+		t.Errorf("ParseConflictPolicy(skip) = %s, %v", p, err)
+	}
+	if _, err := storage.ParseConflictPolicy("merge"); !errors.Is(err, storage.ErrUnknownConflictPolicy) {
+		t.Errorf("Expected ErrUnknownConflictPolicy, got: %v", err)
+	}
+}
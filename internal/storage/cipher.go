@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+var (
+	ErrIntegrity = errors.New("value authentication failed: wrong encryption key, or the value was moved or modified")
+)
+
+// Cipher encrypts and decrypts values of keys in a bucket.
+type Cipher interface {
+	Seal(bucket, key, value string) (string, error)
+	Open(bucket, key, encValue string) (string, error)
+}
+
+// KeyCipher is a Cipher with an AES encryption key.
+type KeyCipher string
+
+// Seal encrypts a value of the key in the bucket.
+func (k KeyCipher) Seal(bucket, key, value string) (string, error) {
+	return sealValue(string(k), bucket, key, value)
+}
+
+// Open decrypts a value of the key in the bucket.
+func (k KeyCipher) Open(bucket, key, encValue string) (string, error) {
+	return openValue(string(k), bucket, key, encValue)
+}
+
+// RawKeyCipher is a Cipher with raw AES key bytes. The key is used in place and is not copied,
+// so it can be kept in locked memory.
+type RawKeyCipher []byte
+
+// Seal encrypts a value of the key in the bucket.
+func (k RawKeyCipher) Seal(bucket, key, value string) (string, error) {
+	return encrypt.NewAESWithRawKey(k, value).WithAdditionalData(additionalData(bucket, key)).Encrypt()
+}
+
+// Open decrypts a value of the key in the bucket.
+func (k RawKeyCipher) Open(bucket, key, encValue string) (string, error) {
+	return openAES(encrypt.NewAESWithRawKey(k, encValue), bucket, key, encValue)
+}
+
+// additionalData binds a value to its bucket and key name.
+// The bucket name is length-prefixed, so different bucket/key pairs never produce the same data.
+func additionalData(bucket, key string) []byte {
+	ad := binary.AppendUvarint(nil, uint64(len(bucket)))
+	ad = append(ad, bucket...)
+	return append(ad, key...)
+}
+
+// sealValue encrypts a value of the key in the bucket.
+// The value is authenticated with the bucket and key name, so it can't be moved to another key.
+func sealValue(encryptionKey, bucket, key, value string) (string, error) {
+	return encrypt.NewAES(encryptionKey, value).WithAdditionalData(additionalData(bucket, key)).Encrypt()
+}
+
+// openValue decrypts a value of the key in the bucket.
+// Values written before the v2 format are not bound to the bucket and key and are accepted as is,
+// EntityStorage rejects them once the database is marked as migrated (MarkMigrated).
+func openValue(encryptionKey, bucket, key, encValue string) (string, error) {
+	return openAES(encrypt.NewAES(encryptionKey, encValue), bucket, key, encValue)
+}
+
+// openAES decrypts encValue of the key in the bucket with a.
+func openAES(a *encrypt.AES, bucket, key, encValue string) (string, error) {
+	value, err := a.WithAdditionalData(additionalData(bucket, key)).Decrypt()
+	if err != nil && encrypt.IsV2(encValue) && errors.Is(err, encrypt.ErrorDecryptionFailed) {
+		return "", fmt.Errorf("%w: %v", ErrIntegrity, err)
+	}
+	return value, err
+}
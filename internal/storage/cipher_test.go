@@ -0,0 +1,175 @@
+package storage_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/storage"
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+// getRaw reads a stored value bypassing EntityStorage.
+func getRaw(t *testing.T, db *bbolt.DB, bucket, key string) string {
+	var v string
+	_ = db.View(func(tx *bbolt.Tx) error {
+		v = string(tx.Bucket([]byte(bucket)).Get([]byte(key)))
+		return nil
+	})
+	return v
+}
+
+func TestValueBoundToKey(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.Add("prod", "token", "prod-token")
+	_ = s.Add("prod", "password", "prod-password")
+	_ = s.Add("stage", "token", "stage-token")
+
+	if raw := getRaw(t, db, "prod", "token"); !strings.Contains(raw, encrypt.PrefixV2) {
+		t.Fatalf("expected v2 ciphertext, got %s", raw)
+	}
+
+	// swap values between keys and buckets sharing an encryption key
+	putRaw(t, db, "prod", "password", getRaw(t, db, "prod", "token"))
+	putRaw(t, db, "prod", "token", getRaw(t, db, "stage", "token"))
+
+	for _, key := range []string{"password", "token"} {
+		if _, err := s.Get("prod", key); !errors.Is(err, storage.ErrIntegrity) {
+			t.Errorf("Get moved value %s: expected ErrIntegrity, got: %v", key, err)
+		}
+	}
+	if _, err := s.List("prod", storage.ListOptions{Values: true}); !errors.Is(err, storage.ErrIntegrity) {
+		t.Errorf("List with moved values: expected ErrIntegrity, got: %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key := mustGenKey(t)
+	putRaw(t, db, "prod", "legacy", mustEncrypt(t, key, "old value"))
+
+	s := storage.NewEntityStorage(db, key)
+	_ = s.Add("prod", "current", "new value")
+	_ = s.Add("prod", "legacy", "updated") // keeps the legacy value as version 1
+
+	n, err := s.Migrate([]string{"prod"})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 migrated value, got %d", n)
+	}
+
+	if val, err := s.GetVersion("prod", "legacy", 1); err != nil || val != "old value" {
+		t.Errorf("GetVersion after migrate = '%s', err: %v", val, err)
+	}
+	if n, _ := s.Migrate([]string{"prod"}); n != 0 {
+		t.Errorf("Expected nothing to migrate twice, got %d", n)
+	}
+}
+
+func TestMarkMigrated(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key := mustGenKey(t)
+	s := storage.NewEntityStorage(db, key)
+	putRaw(t, db, "prod", "legacy", mustEncrypt(t, key, "old value"))
+	if _, err := s.Migrate([]string{"prod"}); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if err := s.MarkMigrated(); err != nil {
+		t.Fatalf("MarkMigrated failed: %v", err)
+	}
+	if ok, err := s.Migrated(); err != nil || !ok {
+		t.Fatalf("Migrated = %v, %v; want true", ok, err)
+	}
+	if val, err := s.Get("prod", "legacy"); err != nil || val != "old value" {
+		t.Errorf("Get migrated value = '%s', err: %v", val, err)
+	}
+
+	// a legacy value put back into the file is rejected
+	putRaw(t, db, "prod", "swapped", mustEncrypt(t, key, "old value"))
+	if _, err := s.Get("prod", "swapped"); !errors.Is(err, storage.ErrLegacyValue) {
+		t.Errorf("Get legacy value: expected ErrLegacyValue, got: %v", err)
+	}
+	if _, err := s.List("prod", storage.ListOptions{Values: true}); !errors.Is(err, storage.ErrLegacyValue) {
+		t.Errorf("List legacy value: expected ErrLegacyValue, got: %v", err)
+	}
+	if _, err := s.Migrate([]string{"prod"}); !errors.Is(err, storage.ErrLegacyValue) {
+		t.Errorf("Migrate legacy value: expected ErrLegacyValue, got: %v", err)
+	}
+
+	// the marker is not a data bucket
+	if buckets, _ := s.ListBuckets(); len(buckets) != 1 || buckets[0] != "prod" {
+		t.Errorf("ListBuckets = %v; want [prod]", buckets)
+	}
+}
+
+// countingCipher counts calls of a KeyCipher.
+type countingCipher struct {
+	storage.KeyCipher
+	seal, open int
+}
+
+func (c *countingCipher) Seal(bucket, key, value string) (string, error) {
+	c.seal++
+	return c.KeyCipher.Seal(bucket, key, value)
+}
+
+func (c *countingCipher) Open(bucket, key, encValue string) (string, error) {
+	c.open++
+	return c.KeyCipher.Open(bucket, key, encValue)
+}
+
+func TestStorageWithCipher(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key := mustGenKey(t)
+	c := &countingCipher{KeyCipher: storage.KeyCipher(key)}
+	s := storage.NewEntityStorageWithCipher(db, c)
+	if err := s.Add("prod", "token", "secret"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if v, err := s.Get("prod", "token"); err != nil || v != "secret" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if c.seal != 1 || c.open != 1 {
+		t.Errorf("cipher calls: seal %d, open %d, want 1 and 1", c.seal, c.open)
+	}
+
+	// values are compatible with a storage using the key
+	if v, err := storage.NewEntityStorage(db, key).Get("prod", "token"); err != nil || v != "secret" {
+		t.Errorf("Get with key = %q, %v", v, err)
+	}
+
+	if _, err := s.Migrate([]string{"prod"}); !errors.Is(err, storage.ErrNoEncryptionKey) {
+		t.Errorf("Migrate: expected ErrNoEncryptionKey, got: %v", err)
+	}
+}
+
+func TestRawKeyCipher(t *testing.T) {
+	key := mustGenKey(t)
+	raw, err := encrypt.DecodeAESKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := storage.KeyCipher(key).Seal("prod", "token", "secret")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if got, err := storage.RawKeyCipher(raw).Open("prod", "token", v); err != nil || got != "secret" {
+		t.Errorf("Open = '%s', err: %v", got, err)
+	}
+	if _, err := storage.RawKeyCipher(raw).Open("prod", "password", v); !errors.Is(err, storage.ErrIntegrity) {
+		t.Errorf("Expected ErrIntegrity for a moved value, got: %v", err)
+	}
+}
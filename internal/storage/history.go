@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"go.etcd.io/bbolt"
+	bboltErr "go.etcd.io/bbolt/errors"
+)
+
+// historyBucket is a nested bucket inside every data bucket that keeps
+// earlier versions of keys, one nested bucket per key:
+//
+//	<bucket>/<historyBucket>/<key>/<version uint64 BE> => versionRecord (json)
+//
+// The name contains a NUL byte, so it can't clash with a key passed from the command line.
+const historyBucket = "\x00history"
+
+// HistoryLimit is the number of the latest versions kept for a key,
+// older versions are removed when a new one is stored.
+const HistoryLimit = 50
+
+var (
+	ErrVersionNotFound = errors.New("version not found")
+)
+
+// versionRecord is a stored revision of a key value.
+type versionRecord struct {
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Value     string    `json:"value"`
+}
+
+// History returns all stored versions of a key, oldest first.
+// A key written before history was introduced has a single version 1.
+func (d *EntityStorage) History(bucket string, key string) ([]models.Version, error) {
+	var versions []models.Version
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return bboltErr.ErrBucketNotFound
+		}
+		return forEachVersion(b, key, func(v uint64, r versionRecord) error {
+			versions = append(versions, models.Version{Version: v, CreatedAt: r.CreatedAt})
+			return nil
+		})
+	})
+	return versions, err
+}
+
+// GetVersion retrieves and decrypts the given version of a key.
+// Versions of an expired key return ErrExpired.
+func (d *EntityStorage) GetVersion(bucket string, key string, version uint64) (string, error) {
+	var value string
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return bboltErr.ErrBucketNotFound
+		}
+		if data := b.Get([]byte(key)); data != nil {
+			cur, err := decodeRecord(data)
+			if err != nil {
+				return err
+			}
+			if cur.Expired(time.Now()) {
+				return fmt.Errorf("%w at %s", ErrExpired, cur.ExpiresAt.Format(time.RFC3339))
+			}
+		}
+		r, err := getVersion(b, key, version)
+		if err != nil {
+			return err
+		}
+		value, err = d.open(tx, bucket, key, r.Value)
+		return err
+	})
+	return value, err
+}
+
+// Rollback makes the given version of a key current again.
+// The restored value is stored as a new version, so the rollback itself can be undone.
+// The expiry of the version is restored with it, a version stored without expiry
+// keeps the current expiry of the key.
+func (d *EntityStorage) Rollback(bucket string, key string, version uint64) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return bboltErr.ErrBucketNotFound
+		}
+		r, err := getVersion(b, key, version)
+		if err != nil {
+			return err
+		}
+		// make sure the value can be decrypted before restoring it
+		if _, err := d.open(tx, bucket, key, r.Value); err != nil {
+			return err
+		}
+		cur, err := decodeRecord(b.Get([]byte(key)))
+		if err != nil {
+			return err
+		}
+		expiresAt := r.ExpiresAt
+		if expiresAt.IsZero() {
+			expiresAt = cur.ExpiresAt
+		}
+		if err := appendVersion(b, key, r.Value, expiresAt); err != nil {
+			return err
+		}
+		data, err := cur.update(r.Value, models.Metadata{ExpiresAt: expiresAt}).encode()
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+// appendVersion stores encValue with its expiry as the next version of a key
+// and removes versions beyond HistoryLimit.
+// If the key exists but has no history yet, the current value is saved as version 1 first.
+func appendVersion(b *bbolt.Bucket, key string, encValue string, expiresAt time.Time) error {
+	hb, err := b.CreateBucketIfNotExists([]byte(historyBucket))
+	if err != nil {
+		return err
+	}
+	kb, err := hb.CreateBucketIfNotExists([]byte(key))
+	if err != nil {
+		return err
+	}
+
+	var last uint64
+	if k, _ := kb.Cursor().Last(); k != nil {
+		last = binary.BigEndian.Uint64(k)
+	} else if cur := b.Get([]byte(key)); cur != nil {
+		r, err := decodeRecord(cur)
+		if err != nil {
+			return err
+		}
+		if err := putVersion(kb, 1, versionRecord{CreatedAt: r.UpdatedAt, ExpiresAt: r.ExpiresAt, Value: r.Value}); err != nil {
+			return err
+		}
+		last = 1
+	}
+
+	if err := putVersion(kb, last+1, versionRecord{CreatedAt: time.Now().UTC(), ExpiresAt: expiresAt, Value: encValue}); err != nil {
+		return err
+	}
+	return pruneVersions(kb, last+1)
+}
+
+// pruneVersions removes versions older than the HistoryLimit latest ones,
+// where latest is the last stored version.
+func pruneVersions(kb *bbolt.Bucket, latest uint64) error {
+	if latest <= HistoryLimit {
+		return nil
+	}
+	oldest := latest - HistoryLimit + 1
+	c := kb.Cursor()
+	for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) < oldest; k, _ = c.First() {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getVersion returns a stored version of a key.
+func getVersion(b *bbolt.Bucket, key string, version uint64) (versionRecord, error) {
+	var rec versionRecord
+
+	var kb *bbolt.Bucket
+	if hb := b.Bucket([]byte(historyBucket)); hb != nil {
+		kb = hb.Bucket([]byte(key))
+	}
+	if kb == nil {
+		cur := b.Get([]byte(key))
+		if cur == nil {
+			return rec, ErrValueIsEmpty
+		}
+		// written before history was introduced, the current value is version 1
+		if version != 1 {
+			return rec, ErrVersionNotFound
+		}
+		r, err := decodeRecord(cur)
+		if err != nil {
+			return rec, err
+		}
+		return versionRecord{CreatedAt: r.UpdatedAt, ExpiresAt: r.ExpiresAt, Value: r.Value}, nil
+	}
+
+	data := kb.Get(versionKey(version))
+	if data == nil {
+		return rec, ErrVersionNotFound
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, err
+	}
+	return rec, nil
+}
+
+// forEachVersion calls fn for every version of a key in ascending order.
+func forEachVersion(b *bbolt.Bucket, key string, fn func(v uint64, r versionRecord) error) error {
+	cur := b.Get([]byte(key))
+
+	var kb *bbolt.Bucket
+	if hb := b.Bucket([]byte(historyBucket)); hb != nil {
+		kb = hb.Bucket([]byte(key))
+	}
+	if kb == nil {
+		if cur == nil {
+			return ErrValueIsEmpty
+		}
+		// written before history was introduced
+		r, err := decodeRecord(cur)
+		if err != nil {
+			return err
+		}
+		return fn(1, versionRecord{CreatedAt: r.UpdatedAt, ExpiresAt: r.ExpiresAt, Value: r.Value})
+	}
+
+	return kb.ForEach(func(k, v []byte) error {
+		var r versionRecord
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		return fn(binary.BigEndian.Uint64(k), r)
+	})
+}
+
+// putVersion stores a version record.
+func putVersion(kb *bbolt.Bucket, version uint64, r versionRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return kb.Put(versionKey(version), data)
+}
+
+// versionKey returns the history bucket key of a version.
+func versionKey(version uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, version)
+}
+
+// deleteHistory removes all stored versions of a key.
+func deleteHistory(b *bbolt.Bucket, key string) error {
+	hb := b.Bucket([]byte(historyBucket))
+	if hb == nil {
+		return nil
+	}
+	if err := hb.DeleteBucket([]byte(key)); err != nil && !errors.Is(err, bboltErr.ErrBucketNotFound) {
+		return err
+	}
+	return nil
+}
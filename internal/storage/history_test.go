@@ -0,0 +1,200 @@
+package storage_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func TestHistoryKeepsVersions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := s.Add(storage.DefaultBucket, "token", v); err != nil {
+			t.Fatalf("Add(%s) failed: %v", v, err)
+		}
+	}
+
+	versions, err := s.History(storage.DefaultBucket, "token")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %d", len(versions))
+	}
+	for i, v := range versions {
+		if v.Version != uint64(i+1) {
+			t.Errorf("versions[%d].Version = %d; want %d", i, v.Version, i+1)
+		}
+		if v.CreatedAt.IsZero() {
+			t.Errorf("versions[%d].CreatedAt is zero", i)
+		}
+	}
+
+	val, err := s.GetVersion(storage.DefaultBucket, "token", 1)
+	if err != nil {
+		t.Fatalf("GetVersion failed: %v", err)
+	}
+	if val != "v1" {
+		t.Errorf("Expected 'v1', got '%s'", val)
+	}
+
+	for _, v := range []uint64{0, 4, 42} {
+		if _, err := s.GetVersion(storage.DefaultBucket, "token", v); !errors.Is(err, storage.ErrVersionNotFound) {
+			t.Errorf("GetVersion(%d): expected ErrVersionNotFound, got: %v", v, err)
+		}
+	}
+	if _, err := s.GetVersion(storage.DefaultBucket, "missing", 1); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("GetVersion of a missing key: expected ErrValueIsEmpty, got: %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.Add(storage.DefaultBucket, "token", "good")
+	_ = s.Add(storage.DefaultBucket, "token", "bad")
+
+	if err := s.Rollback(storage.DefaultBucket, "token", 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	val, err := s.Get(storage.DefaultBucket, "token")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if val != "good" {
+		t.Errorf("Expected 'good', got '%s'", val)
+	}
+
+	versions, _ := s.History(storage.DefaultBucket, "token")
+	if len(versions) != 3 {
+		t.Errorf("Expected rollback to add a version, got %d versions", len(versions))
+	}
+}
+
+func TestRollbackRestoresExpiry(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	_ = s.AddWithMetadata(storage.DefaultBucket, "token", "temporary", models.Metadata{ExpiresAt: expiresAt})
+	_ = s.Add(storage.DefaultBucket, "token", "permanent")
+
+	if err := s.Rollback(storage.DefaultBucket, "token", 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	e, err := s.GetEntity(storage.DefaultBucket, "token")
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if e.Value != "temporary" || !e.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected 'temporary' expiring at %s, got '%s' expiring at %s", expiresAt, e.Value, e.ExpiresAt)
+	}
+
+	// a version without expiry keeps the current expiry
+	if err := s.Rollback(storage.DefaultBucket, "token", 2); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	e, err = s.GetEntity(storage.DefaultBucket, "token")
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if e.Value != "permanent" || !e.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected 'permanent' expiring at %s, got '%s' expiring at %s", expiresAt, e.Value, e.ExpiresAt)
+	}
+}
+
+func TestHistoryForLegacyValue(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key := mustGenKey(t)
+	s := storage.NewEntityStorage(db, key)
+	_ = s.Add(storage.DefaultBucket, "old", "first")
+
+	// drop history to simulate a value written by an older version
+	if err := s.Delete(storage.DefaultBucket, "old"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	putRaw(t, db, storage.DefaultBucket, "old", mustEncrypt(t, key, "first"))
+
+	versions, err := s.History(storage.DefaultBucket, "old")
+	if err != nil || len(versions) != 1 || !versions[0].CreatedAt.IsZero() {
+		t.Fatalf("Expected a single version without timestamp, got %v, err: %v", versions, err)
+	}
+	// the current value without history is version 1
+	if val, err := s.GetVersion(storage.DefaultBucket, "old", 1); err != nil || val != "first" {
+		t.Errorf("GetVersion(1) = '%s', err: %v", val, err)
+	}
+	if _, err := s.GetVersion(storage.DefaultBucket, "old", 2); !errors.Is(err, storage.ErrVersionNotFound) {
+		t.Errorf("GetVersion(2): expected ErrVersionNotFound, got: %v", err)
+	}
+
+	_ = s.Add(storage.DefaultBucket, "old", "second")
+	val, err := s.GetVersion(storage.DefaultBucket, "old", 1)
+	if err != nil || val != "first" {
+		t.Fatalf("Expected legacy value kept as version 1, got '%s', err: %v", val, err)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	total := storage.HistoryLimit + 5
+	for i := 1; i <= total; i++ {
+		if err := s.Add(storage.DefaultBucket, "token", fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatalf("Add(v%d) failed: %v", i, err)
+		}
+	}
+
+	versions, err := s.History(storage.DefaultBucket, "token")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != storage.HistoryLimit {
+		t.Fatalf("Expected %d versions, got %d", storage.HistoryLimit, len(versions))
+	}
+	if versions[0].Version != 6 || versions[len(versions)-1].Version != uint64(total) {
+		t.Errorf("Expected versions 6..%d, got %d..%d", total, versions[0].Version, versions[len(versions)-1].Version)
+	}
+	if _, err := s.GetVersion(storage.DefaultBucket, "token", 5); !errors.Is(err, storage.ErrVersionNotFound) {
+		t.Errorf("GetVersion(5): expected ErrVersionNotFound, got: %v", err)
+	}
+	if val, err := s.GetVersion(storage.DefaultBucket, "token", 6); err != nil || val != "v6" {
+		t.Errorf("GetVersion(6) = '%s', err: %v", val, err)
+	}
+}
+
+func TestDeleteRemovesHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.Add(storage.DefaultBucket, "k", "v1")
+	_ = s.Add(storage.DefaultBucket, "k", "v2")
+	_ = s.Delete(storage.DefaultBucket, "k")
+
+	if _, err := s.History(storage.DefaultBucket, "k"); !errors.Is(err, storage.ErrValueIsEmpty) {
+		t.Errorf("Expected ErrValueIsEmpty, got: %v", err)
+	}
+
+	items, err := s.List(storage.DefaultBucket, storage.ListOptions{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Expected no keys, got %v", items)
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+// metaBucket is a top-level bucket with settings of the database itself:
+//
+//	<metaBucket>/migrated => RFC 3339 time of MarkMigrated
+//
+// It is not a data bucket and is not returned by ListBuckets. The name contains a NUL byte,
+// so it can't clash with a bucket name passed from the command line.
+const metaBucket = "\x00meta"
+
+const migratedKey = "migrated"
+
+var (
+	ErrLegacyValue = errors.New("value is in the format before v2, which is rejected after the database was migrated")
+)
+
+// IsReservedBucket reports whether a top-level bucket name is used by the storage itself
+// and is not a data bucket.
+func IsReservedBucket(name string) bool {
+	return strings.HasPrefix(name, "\x00")
+}
+
+// MarkMigrated records that every value was migrated to the v2 format.
+// After that values written before the v2 format, which are not bound to their bucket
+// and key name, are rejected with ErrLegacyValue instead of being decrypted.
+func (d *EntityStorage) MarkMigrated() error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(migratedKey), []byte(time.Now().UTC().Format(time.RFC3339)))
+	})
+}
+
+// Migrated reports whether the database was marked as migrated by MarkMigrated.
+func (d *EntityStorage) Migrated() (bool, error) {
+	var ok bool
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		ok = migrated(tx)
+		return nil
+	})
+	return ok, err
+}
+
+// migrated reports whether the database was marked as migrated.
+func migrated(tx *bbolt.Tx) bool {
+	b := tx.Bucket([]byte(metaBucket))
+	return b != nil && b.Get([]byte(migratedKey)) != nil
+}
+
+// checkFormat returns ErrLegacyValue for a value written before the v2 format
+// in a migrated database.
+func checkFormat(tx *bbolt.Tx, encValue string) error {
+	if !encrypt.IsV2(encValue) && migrated(tx) {
+		return ErrLegacyValue
+	}
+	return nil
+}
+
+// open decrypts a stored value of the key in the bucket with the storage cipher.
+func (d *EntityStorage) open(tx *bbolt.Tx, bucket, key, encValue string) (string, error) {
+	if err := checkFormat(tx, encValue); err != nil {
+		return "", err
+	}
+	return d.cipher.Open(bucket, key, encValue)
+}
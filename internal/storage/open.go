@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Open opens the database, waiting up to timeout for the lock held by another process,
+// 0 waits forever. A read-only database shares the lock with other readers, a missing
+// database file is created by a read-write open as there is nothing to read yet.
+// A lock held for longer than timeout returns ErrLockTimeout.
+func Open(path string, timeout time.Duration, readOnly bool) (*bbolt.DB, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		readOnly = false
+	}
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: timeout, ReadOnly: readOnly})
+	if errors.Is(err, ErrLockTimeout) {
+		return nil, fmt.Errorf("open database: database is locked by another kv process, gave up after %s (see --lock-timeout): %w", timeout, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return db, nil
+}
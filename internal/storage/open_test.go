@@ -0,0 +1,77 @@
+package storage_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func TestOpenLockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+	db, err := storage.Open(path, time.Second, false)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	// a second writer gives up after the timeout
+	_, err = storage.Open(path, 100*time.Millisecond, false)
+	if !errors.Is(err, storage.ErrLockTimeout) {
+		t.Fatalf("Expected ErrLockTimeout, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "locked by another kv process") {
+		t.Errorf("Expected the lock message, got: %v", err)
+	}
+
+	// a reader waits for the writer too
+	if _, err := storage.Open(path, 100*time.Millisecond, true); !errors.Is(err, storage.ErrLockTimeout) {
+		t.Errorf("Expected ErrLockTimeout for a reader, got: %v", err)
+	}
+}
+
+func TestOpenReadOnlyShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+	db, err := storage.Open(path, time.Second, false)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := storage.NewEntityStorage(db, mustGenKey(t)).Add("prod", "token", "1"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	r1, err := storage.Open(path, 100*time.Millisecond, true)
+	if err != nil {
+		t.Fatalf("first read-only Open failed: %v", err)
+	}
+	defer r1.Close()
+	r2, err := storage.Open(path, 100*time.Millisecond, true)
+	if err != nil {
+		t.Fatalf("second read-only Open failed: %v", err)
+	}
+	defer r2.Close()
+	if !r1.IsReadOnly() || !r2.IsReadOnly() {
+		t.Error("Expected read-only databases")
+	}
+
+	// a writer waits for the readers
+	if _, err := storage.Open(path, 100*time.Millisecond, false); !errors.Is(err, storage.ErrLockTimeout) {
+		t.Errorf("Expected ErrLockTimeout for a writer, got: %v", err)
+	}
+}
+
+func TestOpenReadOnlyMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+	db, err := storage.Open(path, time.Second, true)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+	if db.IsReadOnly() {
+		t.Error("Expected a missing database to be created read-write")
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+// record is a stored key value: the encrypted value and its plaintext metadata.
+//
+// Values written before metadata was introduced are stored as a bare
+// ciphertext string and are read as a record with empty metadata.
+type record struct {
+	Value string `json:"value"`
+	models.Metadata
+}
+
+// decodeRecord parses a stored value.
+func decodeRecord(data []byte) (record, error) {
+	// ciphertext is never a JSON object, so anything else is a legacy value
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return record{Value: string(data)}, nil
+	}
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("decode record: %w", err)
+	}
+	return r, nil
+}
+
+// encode serializes the record for storage.
+func (r record) encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// update returns a copy of the record with a new value and updated metadata.
+// Description and tags are replaced only when set in meta,
+// expiry always comes from meta since it belongs to the value.
+func (r record) update(encValue string, meta models.Metadata) record {
+	now := time.Now().UTC()
+
+	r.Value = encValue
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = now
+	}
+	r.UpdatedAt = now
+	r.UpdatedBy = writer()
+	if meta.Description != "" {
+		r.Description = meta.Description
+	}
+	if meta.Tags != nil {
+		r.Tags = meta.Tags
+	}
+	r.ExpiresAt = meta.ExpiresAt
+	return r
+}
+
+// writer returns the user@host that writes a value.
+func writer() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		return name + "@" + host
+	}
+	return name
+}
@@ -0,0 +1,176 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func TestAddWithMetadata(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	meta := models.Metadata{
+		Description: "payments api token",
+		Tags:        map[string]string{"team": "payments"},
+	}
+	if err := s.AddWithMetadata(storage.DefaultBucket, "token", "v1", meta); err != nil {
+		t.Fatalf("AddWithMetadata failed: %v", err)
+	}
+
+	items, err := s.List(storage.DefaultBucket, storage.ListOptions{Values: true})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("List failed: %v, items: %v", err, items)
+	}
+	e := items[0]
+	if e.Value != "v1" || e.Description != meta.Description || e.Tags["team"] != "payments" {
+		t.Errorf("unexpected entity: %+v", e)
+	}
+	if e.CreatedAt.IsZero() || e.UpdatedAt.IsZero() || e.UpdatedBy == "" {
+		t.Errorf("expected timestamps and writer to be set: %+v", e)
+	}
+
+	// update without metadata keeps description, tags and created_at
+	if err := s.Add(storage.DefaultBucket, "token", "v2"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	items, _ = s.List(storage.DefaultBucket, storage.ListOptions{Values: true})
+	u := items[0]
+	if u.Value != "v2" || u.Description != meta.Description || u.Tags["team"] != "payments" {
+		t.Errorf("metadata not preserved on update: %+v", u)
+	}
+	if !u.CreatedAt.Equal(e.CreatedAt) {
+		t.Errorf("created_at changed on update: %v != %v", u.CreatedAt, e.CreatedAt)
+	}
+	if u.UpdatedAt.Before(e.UpdatedAt) {
+		t.Errorf("updated_at went back: %v < %v", u.UpdatedAt, e.UpdatedAt)
+	}
+}
+
+func TestLegacyValueWithoutMetadata(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key := mustGenKey(t)
+	putRaw(t, db, storage.DefaultBucket, "legacy", mustEncrypt(t, key, "old value"))
+
+	s := storage.NewEntityStorage(db, key)
+	val, err := s.Get(storage.DefaultBucket, "legacy")
+	if err != nil || val != "old value" {
+		t.Fatalf("Get legacy value = '%s', err: %v", val, err)
+	}
+
+	items, err := s.List(storage.DefaultBucket, storage.ListOptions{Values: true})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("List failed: %v, items: %v", err, items)
+	}
+	if items[0].Value != "old value" || !items[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected legacy entity: %+v", items[0])
+	}
+}
+
+func TestMatchTags(t *testing.T) {
+	m := models.Metadata{Tags: map[string]string{"team": "payments", "env": "prod"}}
+
+	cases := []struct {
+		filter map[string]string
+		want   bool
+	}{
+		{nil, true},
+		{map[string]string{"team": "payments"}, true},
+		{map[string]string{"team": ""}, true},
+		{map[string]string{"team": "payments", "env": "prod"}, true},
+		{map[string]string{"team": "billing"}, false},
+		{map[string]string{"owner": ""}, false},
+	}
+	for _, c := range cases {
+		if got := m.MatchTags(c.filter); got != c.want {
+			t.Errorf("MatchTags(%v) = %v; want %v", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestExpiredKey(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	past := models.Metadata{ExpiresAt: time.Now().Add(-time.Hour)}
+	future := models.Metadata{ExpiresAt: time.Now().Add(time.Hour)}
+	_ = s.AddWithMetadata(storage.DefaultBucket, "old-token", "v1", past)
+	_ = s.AddWithMetadata(storage.DefaultBucket, "new-token", "v2", future)
+	_ = s.Add(storage.DefaultBucket, "forever", "v3")
+
+	if _, err := s.Get(storage.DefaultBucket, "old-token"); !errors.Is(err, storage.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got: %v", err)
+	}
+	if val, err := s.Get(storage.DefaultBucket, "new-token"); err != nil || val != "v2" {
+		t.Errorf("Get not expired key = '%s', err: %v", val, err)
+	}
+
+	purged, err := s.PurgeExpired(storage.DefaultBucket, time.Now())
+	if err != nil {
+		t.Fatalf("PurgeExpired failed: %v", err)
+	}
+	if len(purged) != 1 || purged[0] != "old-token" {
+		t.Errorf("Expected only 'old-token' purged, got %v", purged)
+	}
+
+	items, _ := s.List(storage.DefaultBucket, storage.ListOptions{})
+	if len(items) != 2 {
+		t.Errorf("Expected 2 keys after purge, got %v", items)
+	}
+}
+
+// TestListExpired covers the options used by exec, list keys --values, render and the server:
+// decrypted values of expired keys are never returned.
+func TestListExpired(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.AddWithMetadata(storage.DefaultBucket, "old-token", "v1", models.Metadata{ExpiresAt: time.Now().Add(-time.Hour)})
+	_ = s.Add(storage.DefaultBucket, "token", "v2")
+
+	items, err := s.List(storage.DefaultBucket, storage.ListOptions{Values: true, SkipExpired: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Key != "token" || items[0].Value != "v2" {
+		t.Errorf("Expected only 'token' with its value, got %v", items)
+	}
+
+	items, err = s.List(storage.DefaultBucket, storage.ListOptions{Values: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, e := range items {
+		if e.Key == "old-token" && e.Value != "" {
+			t.Errorf("Expected no value for the expired key, got '%s'", e.Value)
+		}
+	}
+	if len(items) != 2 {
+		t.Errorf("Expected 2 keys, got %v", items)
+	}
+
+	if _, err := s.GetVersion(storage.DefaultBucket, "old-token", 1); !errors.Is(err, storage.ErrExpired) {
+		t.Errorf("Expected ErrExpired for a version of an expired key, got: %v", err)
+	}
+}
+
+func TestUpdateClearsExpiry(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.AddWithMetadata(storage.DefaultBucket, "token", "v1", models.Metadata{ExpiresAt: time.Now().Add(-time.Hour)})
+	_ = s.Add(storage.DefaultBucket, "token", "v2")
+
+	if val, err := s.Get(storage.DefaultBucket, "token"); err != nil || val != "v2" {
+		t.Errorf("Get updated key = '%s', err: %v", val, err)
+	}
+}
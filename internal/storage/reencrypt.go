@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	ErrNoDecryptionKey = errors.New("value can't be decrypted with any of the provided keys")
+	ErrNoEncryptionKey = errors.New("storage has no encryption key")
+)
+
+// rewriteFunc returns a new encrypted value for a key value or history version,
+// or changed == false to keep the value as is.
+type rewriteFunc func(key string, encValue string) (newValue string, changed bool, err error)
+
+// Reencrypt decrypts every value in the buckets, including key history, with one of oldKeys
+// and encrypts it with the storage encryption key. All buckets are processed in a single
+// transaction, so either every value is re-encrypted or none is.
+// Buckets that don't exist are skipped. It returns the number of re-encrypted values.
+func (d *EntityStorage) Reencrypt(buckets []string, oldKeys []string) (int, error) {
+	if d.encryptionKey == "" {
+		return 0, ErrNoEncryptionKey
+	}
+	return d.rewrite(buckets, func(bucket string) rewriteFunc {
+		return func(key string, encValue string) (string, bool, error) {
+			for _, k := range oldKeys {
+				plain, err := openValue(k, bucket, key, encValue)
+				if err != nil {
+					continue
+				}
+				v, err := sealValue(d.encryptionKey, bucket, key, plain)
+				return v, true, err
+			}
+			return "", false, ErrNoDecryptionKey
+		}
+	})
+}
+
+// Migrate upgrades values in the buckets, including key history, written before the v2
+// format to the v2 format bound to their bucket and key name. All buckets are processed
+// in a single transaction. It returns the number of upgraded values.
+// Run MarkMigrated after all buckets are migrated to reject such values from then on.
+func (d *EntityStorage) Migrate(buckets []string) (int, error) {
+	if d.encryptionKey == "" {
+		return 0, ErrNoEncryptionKey
+	}
+	return d.rewrite(buckets, func(bucket string) rewriteFunc {
+		return func(key string, encValue string) (string, bool, error) {
+			if encrypt.IsV2(encValue) {
+				return "", false, nil
+			}
+			plain, err := encrypt.NewAES(d.encryptionKey, encValue).Decrypt()
+			if err != nil {
+				return "", false, err
+			}
+			v, err := sealValue(d.encryptionKey, bucket, key, plain)
+			return v, true, err
+		}
+	})
+}
+
+// rewrite applies the function returned by fn for a bucket to every value of the bucket
+// in a single transaction. In a migrated database a value written before the v2 format
+// fails the rewrite with ErrLegacyValue. It returns the number of changed values.
+func (d *EntityStorage) rewrite(buckets []string, fn func(bucket string) rewriteFunc) (int, error) {
+	var count int
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				continue
+			}
+			rewriteValue := fn(name)
+			n, err := rewriteBucket(b, func(key string, encValue string) (string, bool, error) {
+				if err := checkFormat(tx, encValue); err != nil {
+					return "", false, err
+				}
+				return rewriteValue(key, encValue)
+			})
+			if err != nil {
+				return fmt.Errorf("bucket '%s': %w", name, err)
+			}
+			count += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// rewriteBucket rewrites current values and history of all keys in a bucket.
+func rewriteBucket(b *bbolt.Bucket, fn rewriteFunc) (int, error) {
+	updates := make(map[string][]byte)
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+		r, err := decodeRecord(v)
+		if err != nil {
+			return fmt.Errorf("key '%s': %w", k, err)
+		}
+		newValue, changed, err := fn(string(k), r.Value)
+		if err != nil {
+			return fmt.Errorf("key '%s': %w", k, err)
+		}
+		if !changed {
+			return nil
+		}
+		r.Value = newValue
+		data, err := r.encode()
+		if err != nil {
+			return err
+		}
+		updates[string(k)] = data
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for k, data := range updates {
+		if err := b.Put([]byte(k), data); err != nil {
+			return 0, err
+		}
+	}
+	count := len(updates)
+
+	hb := b.Bucket([]byte(historyBucket))
+	if hb == nil {
+		return count, nil
+	}
+	var keys [][]byte
+	err = hb.ForEachBucket(func(key []byte) error {
+		keys = append(keys, bytes.Clone(key))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		kb := hb.Bucket(key)
+		versions := make(map[string][]byte)
+		err := kb.ForEach(func(k, v []byte) error {
+			var r versionRecord
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			newValue, changed, err := fn(string(key), r.Value)
+			if err != nil {
+				return fmt.Errorf("key '%s' history: %w", key, err)
+			}
+			if !changed {
+				return nil
+			}
+			r.Value = newValue
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			versions[string(k)] = data
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		for k, data := range versions {
+			if err := kb.Put([]byte(k), data); err != nil {
+				return 0, err
+			}
+		}
+		count += len(versions)
+	}
+	return count, nil
+}
@@ -0,0 +1,58 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func TestReencrypt(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	oldKey, newKey := mustGenKey(t), mustGenKey(t)
+	old := storage.NewEntityStorage(db, oldKey)
+	_ = old.Add("prod", "token", "v1")
+	_ = old.Add("prod", "token", "v2")
+	_ = old.Add("prod", "password", "secret")
+
+	s := storage.NewEntityStorage(db, newKey)
+	n, err := s.Reencrypt([]string{"prod", "missing"}, []string{mustGenKey(t), oldKey})
+	if err != nil {
+		t.Fatalf("Reencrypt failed: %v", err)
+	}
+	// 2 current values + 3 history versions
+	if n != 5 {
+		t.Errorf("Expected 5 re-encrypted values, got %d", n)
+	}
+
+	if val, err := s.Get("prod", "token"); err != nil || val != "v2" {
+		t.Errorf("Get after reencrypt = '%s', err: %v", val, err)
+	}
+	if val, err := s.GetVersion("prod", "token", 1); err != nil || val != "v1" {
+		t.Errorf("GetVersion after reencrypt = '%s', err: %v", val, err)
+	}
+	if _, err := old.Get("prod", "token"); err == nil {
+		t.Error("Expected old key to fail after reencrypt")
+	}
+}
+
+func TestReencryptIsAtomic(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key1, key2 := mustGenKey(t), mustGenKey(t)
+	_ = storage.NewEntityStorage(db, key1).Add("prod", "a", "1")
+	_ = storage.NewEntityStorage(db, key2).Add("prod", "b", "2")
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	if _, err := s.Reencrypt([]string{"prod"}, []string{key1}); !errors.Is(err, storage.ErrNoDecryptionKey) {
+		t.Fatalf("Expected ErrNoDecryptionKey, got: %v", err)
+	}
+
+	// nothing changed
+	if val, err := storage.NewEntityStorage(db, key1).Get("prod", "a"); err != nil || val != "1" {
+		t.Errorf("Get after failed reencrypt = '%s', err: %v", val, err)
+	}
+}
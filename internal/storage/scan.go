@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+	bboltErr "go.etcd.io/bbolt/errors"
+)
+
+// ScanFunc is called for a stored encrypted value of a key. Version is 0 for the current
+// value and the version number for a value in the key history.
+type ScanFunc func(key string, version uint64, encValue string) error
+
+// Scan calls fn for the current value and every history version of all keys in a bucket
+// without decrypting them. It returns ErrBucketNotFound if the bucket doesn't exist.
+func (d *EntityStorage) Scan(bucket string, fn ScanFunc) error {
+	return d.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return bboltErr.ErrBucketNotFound
+		}
+		err := b.ForEach(func(k, v []byte) error {
+			// skip nested buckets (key history)
+			if v == nil {
+				return nil
+			}
+			r, err := decodeRecord(v)
+			if err != nil {
+				return fmt.Errorf("key '%s': %w", k, err)
+			}
+			return fn(string(k), 0, r.Value)
+		})
+		if err != nil {
+			return err
+		}
+
+		hb := b.Bucket([]byte(historyBucket))
+		if hb == nil {
+			return nil
+		}
+		return hb.ForEachBucket(func(key []byte) error {
+			return hb.Bucket(key).ForEach(func(k, v []byte) error {
+				var r versionRecord
+				if err := json.Unmarshal(v, &r); err != nil {
+					return fmt.Errorf("key '%s' history: %w", key, err)
+				}
+				return fn(string(key), binary.BigEndian.Uint64(k), r.Value)
+			})
+		})
+	})
+}
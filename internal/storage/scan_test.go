@@ -0,0 +1,41 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/storage"
+)
+
+func TestScan(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	_ = s.Add("prod", "token", "v1")
+	_ = s.Add("prod", "token", "v2")
+	_ = s.Add("prod", "password", "secret")
+
+	var current, versions int
+	err := s.Scan("prod", func(key string, version uint64, encValue string) error {
+		if encValue == "" {
+			t.Errorf("Expected encrypted value for key '%s' version %d", key, version)
+		}
+		if version == 0 {
+			current++
+		} else {
+			versions++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if current != 2 || versions != 3 {
+		t.Errorf("Expected 2 current values and 3 versions, got %d and %d", current, versions)
+	}
+
+	if err := s.Scan("missing", func(string, uint64, string) error { return nil }); !errors.Is(err, storage.ErrBucketNotFound) {
+		t.Errorf("Expected ErrBucketNotFound, got: %v", err)
+	}
+}
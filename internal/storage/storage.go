@@ -3,9 +3,10 @@ package storage
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/yousysadmin/kv/internal/models"
-	"github.com/yousysadmin/kv/pkg/encrypt"
 	"go.etcd.io/bbolt"
 	bboltErr "go.etcd.io/bbolt/errors"
 )
@@ -13,24 +14,45 @@ import (
 const DefaultBucket = "default"
 
 var (
-	ErrValueIsEmpty = errors.New("key not found or value is empty")
+	ErrValueIsEmpty   = errors.New("key not found or value is empty")
+	ErrExpired        = errors.New("key is expired")
+	ErrInvalidKeyName = errors.New("invalid key name")
+
+	// ErrBucketNotFound is returned for a bucket that doesn't exist.
+	ErrBucketNotFound = bboltErr.ErrBucketNotFound
+	// ErrLockTimeout is returned when the database stays locked by another process
+	// for longer than the open timeout.
+	ErrLockTimeout = bboltErr.ErrTimeout
 )
 
 // EntityStorage persists Entity data in the database.
 type EntityStorage struct {
 	db            *bbolt.DB
 	encryptionKey string
+	cipher        Cipher
 }
 
 // NewEntityStorage creates a new EntityStorage.
 func NewEntityStorage(db *bbolt.DB, encryptionKey string) *EntityStorage {
-	return &EntityStorage{db: db, encryptionKey: encryptionKey}
+	return &EntityStorage{db: db, encryptionKey: encryptionKey, cipher: KeyCipher(encryptionKey)}
+}
+
+// NewEntityStorageWithCipher creates a new EntityStorage that encrypts values with c.
+// Reencrypt and Migrate need the encryption key and fail on such storage.
+func NewEntityStorageWithCipher(db *bbolt.DB, c Cipher) *EntityStorage {
+	return &EntityStorage{db: db, cipher: c}
 }
 
 // Add inserts and encrypts a key-value pair into the specified bucket.
 func (d *EntityStorage) Add(bucket string, key string, value string) error {
-	aes := encrypt.NewAES(d.encryptionKey, value)
-	encValue, err := aes.Encrypt()
+	return d.AddWithMetadata(bucket, key, value, models.Metadata{})
+}
+
+// AddWithMetadata inserts and encrypts a key-value pair into the specified bucket
+// with a description, tags and expiry time from meta. Timestamps and writer are set automatically,
+// an existing description and tags are kept unless set in meta.
+func (d *EntityStorage) AddWithMetadata(bucket string, key string, value string, meta models.Metadata) error {
+	encValue, err := d.cipher.Seal(bucket, key, value)
 	if err != nil {
 		return err
 	}
@@ -40,63 +62,140 @@ func (d *EntityStorage) Add(bucket string, key string, value string) error {
 		if err != nil {
 			return err
 		}
-		return b.Put([]byte(key), []byte(encValue))
+		return putValue(b, key, encValue, meta)
 	})
 }
 
+// ValidateKeyName checks that a key name can be stored.
+// A name must not be empty and must not contain NUL bytes, which are reserved for internal buckets.
+func ValidateKeyName(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidKeyName)
+	}
+	if strings.ContainsRune(key, 0) {
+		return fmt.Errorf("%w: %q contains a NUL byte", ErrInvalidKeyName, key)
+	}
+	return nil
+}
+
+// putValue stores an encrypted value as the new version of a key.
+func putValue(b *bbolt.Bucket, key string, encValue string, meta models.Metadata) error {
+	if err := ValidateKeyName(key); err != nil {
+		return err
+	}
+	var (
+		r   record
+		err error
+	)
+	if cur := b.Get([]byte(key)); cur != nil {
+		if r, err = decodeRecord(cur); err != nil {
+			return err
+		}
+	}
+	if err := appendVersion(b, key, encValue, meta.ExpiresAt); err != nil {
+		return err
+	}
+	data, err := r.update(encValue, meta).encode()
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
 // Get retrieves and decrypts the value associated with the given key in the specified bucket.
+// An expired value returns ErrExpired.
 func (d *EntityStorage) Get(bucket string, key string) (string, error) {
-	var value string
+	e, err := d.GetEntity(bucket, key)
+	if err != nil {
+		return "", err
+	}
+	return e.Value, nil
+}
+
+// GetEntity retrieves and decrypts the value with its metadata.
+// An expired value returns ErrExpired.
+func (d *EntityStorage) GetEntity(bucket string, key string) (models.Entity, error) {
+	var entity models.Entity
 	err := d.db.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
 		if b == nil {
 			return bboltErr.ErrBucketNotFound
 		}
-		encValue := b.Get([]byte(key))
-		if encValue == nil {
+		data := b.Get([]byte(key))
+		if data == nil {
 			return ErrValueIsEmpty
 		}
-		aes := encrypt.NewAES(d.encryptionKey, string(encValue))
-		decValue, err := aes.Decrypt()
+		r, err := decodeRecord(data)
 		if err != nil {
 			return err
 		}
-		value = decValue
+		if r.Expired(time.Now()) {
+			return fmt.Errorf("%w at %s", ErrExpired, r.ExpiresAt.Format(time.RFC3339))
+		}
+		decValue, err := d.open(tx, bucket, key, r.Value)
+		if err != nil {
+			return err
+		}
+		entity = models.Entity{Key: key, Value: decValue, Metadata: r.Metadata}
 		return nil
 	})
-	return value, err
+	return entity, err
 }
 
-// Delete removes the key-value pair from the specified bucket.
+// Delete removes the key-value pair and its history from the specified bucket.
 func (d *EntityStorage) Delete(bucket string, key string) error {
 	return d.db.Update(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
 		if b == nil {
 			return bboltErr.ErrBucketNotFound
 		}
+		if err := deleteHistory(b, key); err != nil {
+			return err
+		}
 		return b.Delete([]byte(key))
 	})
 }
 
-// List returns all keys in the specified bucket, optionally including decrypted values.
-func (d *EntityStorage) List(bucket string, withValues bool) ([]models.Entity, error) {
+// ListOptions of EntityStorage.List.
+type ListOptions struct {
+	// Values decrypts the values of keys. Values of expired keys are never decrypted,
+	// expired keys that are not skipped are returned with an empty value.
+	Values bool
+	// SkipExpired leaves expired keys out of the result.
+	SkipExpired bool
+}
+
+// List returns all keys with metadata in the specified bucket, optionally including decrypted values.
+func (d *EntityStorage) List(bucket string, opts ListOptions) ([]models.Entity, error) {
 	var entries []models.Entity
 
+	now := time.Now()
 	err := d.db.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
 		if b == nil {
 			return bboltErr.ErrBucketNotFound
 		}
 		err := b.ForEach(func(k, v []byte) error {
-			if withValues {
-				aes := encrypt.NewAES(d.encryptionKey, string(v))
-				decValue, err := aes.Decrypt()
+			// skip nested buckets (key history)
+			if v == nil {
+				return nil
+			}
+			r, err := decodeRecord(v)
+			if err != nil {
+				return fmt.Errorf("key '%s': %w", k, err)
+			}
+			expired := r.Expired(now)
+			if expired && opts.SkipExpired {
+				return nil
+			}
+			if opts.Values && !expired {
+				decValue, err := d.open(tx, bucket, string(k), r.Value)
 				if err != nil {
-					return fmt.Errorf("decrypt value for key '%s', err: %s", k, err)
+					return fmt.Errorf("decrypt value for key '%s', err: %w", k, err)
 				}
-				entries = append(entries, models.Entity{Key: string(k), Value: decValue})
+				entries = append(entries, models.Entity{Key: string(k), Value: decValue, Metadata: r.Metadata})
 			} else {
-				entries = append(entries, models.Entity{Key: string(k), Value: ""})
+				entries = append(entries, models.Entity{Key: string(k), Value: "", Metadata: r.Metadata})
 			}
 
 			return nil
@@ -106,6 +205,47 @@ func (d *EntityStorage) List(bucket string, withValues bool) ([]models.Entity, e
 	return entries, err
 }
 
+// PurgeExpired removes keys that are expired at now (with their history) from the specified bucket.
+// It returns the names of removed keys.
+func (d *EntityStorage) PurgeExpired(bucket string, now time.Time) ([]string, error) {
+	var purged []string
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return bboltErr.ErrBucketNotFound
+		}
+		err := b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			r, err := decodeRecord(v)
+			if err != nil {
+				return fmt.Errorf("key '%s': %w", k, err)
+			}
+			if r.Expired(now) {
+				purged = append(purged, string(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range purged {
+			if err := deleteHistory(b, k); err != nil {
+				return err
+			}
+			if err := b.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
 // AddBucket add new bucket.
 func (d *EntityStorage) AddBucket(name string) error {
 	return d.db.Update(func(tx *bbolt.Tx) error {
@@ -119,6 +259,9 @@ func (d *EntityStorage) ListBuckets() ([]string, error) {
 	var buckets []string
 	err := d.db.View(func(tx *bbolt.Tx) error {
 		return tx.ForEach(func(n []byte, b *bbolt.Bucket) error {
+			if IsReservedBucket(string(n)) {
+				return nil
+			}
 			buckets = append(buckets, string(n))
 			return nil
 		})
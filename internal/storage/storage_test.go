@@ -32,6 +32,28 @@ func mustGenKey(t *testing.T) string {
 	return k
 }
 
+func mustEncrypt(t *testing.T, key, value string) string {
+	v, err := encrypt.NewAES(key, value).Encrypt()
+	if err != nil {
+		t.Fatalf("failed to encrypt value: %v", err)
+	}
+	return v
+}
+
+// putRaw writes a value to the database bypassing EntityStorage.
+func putRaw(t *testing.T, db *bbolt.DB, bucket, key, value string) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), []byte(value))
+	})
+	if err != nil {
+		t.Fatalf("failed to put raw value: %v", err)
+	}
+}
+
 func TestAddAndGet(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -79,6 +101,18 @@ func TestGetNonExistentKey(t *testing.T) {
 	}
 }
 
+func TestAddInvalidKeyName(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := storage.NewEntityStorage(db, mustGenKey(t))
+	for _, key := range []string{"", "a\x00b"} {
+		if err := s.Add(storage.DefaultBucket, key, "v"); !errors.Is(err, storage.ErrInvalidKeyName) {
+			t.Errorf("Add(%q) error = %v, want %v", key, err, storage.ErrInvalidKeyName)
+		}
+	}
+}
+
 func TestDelete(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -105,7 +139,7 @@ func TestListKeysOnly(t *testing.T) {
 	_ = s.Add(storage.DefaultBucket, "k1", "v1")
 	_ = s.Add(storage.DefaultBucket, "k2", "v2")
 
-	items, err := s.List(storage.DefaultBucket, false)
+	items, err := s.List(storage.DefaultBucket, storage.ListOptions{})
 	if err != nil {
 		t.Fatalf("List failed: %v", err)
 	}
@@ -123,7 +157,7 @@ func TestListWithValues(t *testing.T) {
 	_ = s.Add(storage.DefaultBucket, "k1", "v1")
 	_ = s.Add(storage.DefaultBucket, "k2", "v2")
 
-	items, err := s.List(storage.DefaultBucket, true)
+	items, err := s.List(storage.DefaultBucket, storage.ListOptions{Values: true})
 	if err != nil {
 		t.Fatalf("List with values failed: %v", err)
 	}
@@ -205,6 +239,6 @@ func BenchmarkList(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = s.List(storage.DefaultBucket, true)
+		_, _ = s.List(storage.DefaultBucket, storage.ListOptions{Values: true})
 	}
 }
@@ -113,9 +113,9 @@ func toDotenvMultiline(entities []models.Entity, withValues bool) string {
 	return b.String()
 }
 
-// normalizeKey converts a key to uppercase, replaces any non-alphanumeric
+// NormalizeKey converts a key to uppercase, replaces any non-alphanumeric
 // characters with underscores, and trims leading/trailing underscores.
-func normalizeKey(k string) string {
+func NormalizeKey(k string) string {
 	nonAlnum := regexp.MustCompile(`[^A-Za-z0-9_]`)
 	k = strings.ToUpper(k)
 	k = nonAlnum.ReplaceAllString(k, "_")
@@ -127,7 +127,7 @@ func normalizeKey(k string) string {
 func stableMap(entities []models.Entity, withValues bool) ([]string, map[string]string) {
 	emap := make(map[string]string, len(entities))
 	for _, e := range entities {
-		k := normalizeKey(e.Key)
+		k := NormalizeKey(e.Key)
 		if k == "" {
 			k = "_"
 		}
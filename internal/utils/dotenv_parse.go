@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseDotenv parses dotenv content into key values.
+//
+// Both layouts written by ToDotenvMode are understood:
+//   - double quoted values with "\n", "\r", "\t", "\"" and "\\" escapes (DotenvEscaped)
+//   - double quoted values spanning several lines (DotenvMultiline)
+//
+// Single quoted values are taken literally and may span lines as well.
+// Unquoted values are trimmed and an inline " #" comment is removed.
+// Blank lines, "#" comments and an "export " prefix are skipped. Keys are kept as is.
+func ParseDotenv(data string) (map[string]string, error) {
+	vars := make(map[string]string)
+	src := strings.ReplaceAll(data, "\r\n", "\n")
+	line := 0
+
+	for len(src) > 0 {
+		line++
+		var cur string
+		cur, src, _ = strings.Cut(src, "\n")
+
+		s := strings.TrimSpace(cur)
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		s = strings.TrimPrefix(s, "export ")
+
+		key, rest, ok := strings.Cut(s, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			if i := strings.Index(rest, " #"); i >= 0 {
+				rest = rest[:i]
+			}
+			vars[key] = strings.TrimSpace(rest)
+			continue
+		}
+
+		// quoted value, may continue on the next lines
+		start := line
+		quote := rest[0]
+		value, tail, closed := unquote(rest[1:], quote)
+		for !closed && len(src) > 0 {
+			line++
+			cur, src, _ = strings.Cut(src, "\n")
+			var more string
+			more, tail, closed = unquote(cur, quote)
+			value += "\n" + more
+		}
+		if !closed {
+			return nil, fmt.Errorf("line %d: unterminated quoted value for %s", start, key)
+		}
+		tail = strings.TrimSpace(tail)
+		if tail != "" && !strings.HasPrefix(tail, "#") {
+			return nil, fmt.Errorf("line %d: unexpected text after quoted value for %s", line, key)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// unquote reads a quoted value up to the closing quote.
+// It returns the value, the text after the closing quote and whether the quote was closed.
+// Escapes are processed only in double quoted values.
+func unquote(s string, quote byte) (value string, tail string, closed bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), s[i+1:], true
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), "", false
+}
@@ -0,0 +1,83 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+func TestParseDotenv(t *testing.T) {
+	data := `# comment
+export DB_HOST=localhost
+DB_PORT = 5432 # inline comment
+EMPTY=
+DOUBLE="a \"quoted\" \\ value\nnext"
+SINGLE='literal \n $x'
+MULTI="line1
+line2"
+URL=http://host/#anchor
+`
+	got, err := utils.ParseDotenv(data)
+	if err != nil {
+		t.Fatalf("ParseDotenv failed: %v", err)
+	}
+	want := map[string]string{
+		"DB_HOST": "localhost",
+		"DB_PORT": "5432",
+		"EMPTY":   "",
+		"DOUBLE":  "a \"quoted\" \\ value\nnext",
+		"SINGLE":  `literal \n $x`,
+		"MULTI":   "line1\nline2",
+		"URL":     "http://host/#anchor",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotenv() = %#v; want %#v", got, want)
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	for _, data := range []string{
+		"NOVALUE\n",
+		"=value\n",
+		"BAD KEY=value\n",
+		"OPEN=\"never closed\n",
+		"TAIL=\"value\" text\n",
+	} {
+		if _, err := utils.ParseDotenv(data); err == nil {
+			t.Errorf("ParseDotenv(%q) expected error", data)
+		}
+	}
+}
+
+func TestParseDotenvRoundTrip(t *testing.T) {
+	entities := []models.Entity{
+		{Key: "PLAIN", Value: "value"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "QUOTES", Value: `say "hi"`},
+		{Key: "BACKSLASH", Value: `C:\path\n`},
+		{Key: "PEM", Value: "-----BEGIN KEY-----\nabc\r\ndef\n-----END KEY-----"},
+	}
+	want := map[string]string{
+		"PLAIN":     "value",
+		"EMPTY":     "",
+		"QUOTES":    `say "hi"`,
+		"BACKSLASH": `C:\path\n`,
+		"PEM":       "-----BEGIN KEY-----\nabc\ndef\n-----END KEY-----",
+	}
+
+	for _, mode := range []utils.DotenvMode{utils.DotenvEscaped, utils.DotenvMultiline} {
+		out, err := utils.ToDotenvMode(entities, true, mode)
+		if err != nil {
+			t.Fatalf("ToDotenvMode(%s) failed: %v", mode, err)
+		}
+		got, err := utils.ParseDotenv(out)
+		if err != nil {
+			t.Fatalf("ParseDotenv(%s) failed: %v", mode, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip %s = %#v; want %#v", mode, got, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a duration like time.ParseDuration and additionally
+// supports days ("d") and weeks ("w") units, e.g. "7d", "2w", "1d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	for _, u := range []struct {
+		unit string
+		size time.Duration
+	}{
+		{"w", 7 * 24 * time.Hour},
+		{"d", 24 * time.Hour},
+	} {
+		i := strings.Index(rest, u.unit)
+		if i < 0 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(n) * u.size
+		rest = rest[i+1:]
+	}
+
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += d
+	}
+	return total, nil
+}
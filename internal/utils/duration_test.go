@@ -0,0 +1,37 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"720h":   720 * time.Hour,
+		"30m":    30 * time.Minute,
+		"7d":     7 * 24 * time.Hour,
+		"2w":     14 * 24 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"1w1d1h": 193 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := utils.ParseDuration(in)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error = %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseDuration(%q) = %v; want %v", in, got, want)
+		}
+	}
+}
+
+func TestParseDurationInvalid(t *testing.T) {
+	for _, in := range []string{"", "d", "xd", "7x", "1d2d", "-1d"} {
+		if _, err := utils.ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) = nil error; want error", in)
+		}
+	}
+}
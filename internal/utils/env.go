@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+// ToEnvVars converts entities to environment variables.
+// Key names are normalized the same way as for dotenv output
+// and prefixed with prefix as is. Two keys that become the same name
+// return ErrVariableCollision.
+func ToEnvVars(entities []models.Entity, prefix string) (map[string]string, error) {
+	vars := make(map[string]string, len(entities))
+	keys := make(map[string]string, len(entities))
+	for _, e := range entities {
+		name := NormalizeKey(e.Key)
+		if name == "" {
+			name = "_"
+		}
+		name = prefix + name
+		if other, ok := keys[name]; ok {
+			return nil, fmt.Errorf("%w: %q and %q are both %s", ErrVariableCollision, other, e.Key, name)
+		}
+		keys[name] = e.Key
+		vars[name] = e.Value
+	}
+	return vars, nil
+}
+
+// MergeEnv overlays vars on top of environ (KEY=value pairs, as returned by os.Environ).
+// Existing variables keep their position and get a new value, new variables are appended.
+func MergeEnv(environ []string, vars map[string]string) []string {
+	out := make([]string, 0, len(environ)+len(vars))
+	seen := make(map[string]bool, len(vars))
+
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if v, ok := vars[name]; ok {
+			if !seen[name] {
+				out = append(out, name+"="+v)
+				seen[name] = true
+			}
+			continue
+		}
+		out = append(out, kv)
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		if !seen[k] {
+			out = append(out, k+"="+vars[k])
+		}
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+func TestToEnvVars(t *testing.T) {
+	entities := []models.Entity{
+		{Key: "db.host", Value: "localhost"},
+		{Key: "api-token", Value: "secret"},
+	}
+
+	got, err := utils.ToEnvVars(entities, "APP_")
+	if err != nil {
+		t.Fatalf("ToEnvVars() error = %v", err)
+	}
+	want := map[string]string{
+		"APP_DB_HOST":   "localhost",
+		"APP_API_TOKEN": "secret",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ToEnvVars() = %v; want %v", got, want)
+	}
+}
+
+func TestToEnvVarsCollision(t *testing.T) {
+	entities := []models.Entity{
+		{Key: "db.host", Value: "localhost"},
+		{Key: "db-host", Value: "remote"},
+	}
+
+	if _, err := utils.ToEnvVars(entities, ""); !errors.Is(err, utils.ErrVariableCollision) {
+		t.Fatalf("ToEnvVars() error = %v; want ErrVariableCollision", err)
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	environ := []string{"PATH=/bin", "TOKEN=old", "HOME=/root"}
+	vars := map[string]string{"TOKEN": "new", "B": "2", "A": "1"}
+
+	got := utils.MergeEnv(environ, vars)
+	want := []string{"PATH=/bin", "TOKEN=new", "HOME=/root", "A=1", "B=2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MergeEnv() = %v; want %v", got, want)
+	}
+}
+
+func TestMergeEnvEmptyBase(t *testing.T) {
+	got := utils.MergeEnv(nil, map[string]string{"A": "1"})
+	want := []string{"A=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MergeEnv() = %v; want %v", got, want)
+	}
+}
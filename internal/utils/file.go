@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// AtomicWriteFile writes to a temp file, fsyncs file & dir, then renames.
+// Readers see either the old or the new file content, never a partial write.
+func AtomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	return AtomicWrite(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// AtomicWrite is AtomicWriteFile for content written by write.
+// The file is not changed if write returns an error.
+func AtomicWrite(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("chmod temp: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write temp: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("flush temp: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("fsync temp: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename temp -> %s: %w", path, err)
+	}
+
+	// fsync the directory to persist the rename on some filesystems
+	dirFD, err := os.Open(dir)
+	if err == nil {
+		_ = dirFD.Sync()
+		_ = dirFD.Close()
+	}
+	return nil
+}
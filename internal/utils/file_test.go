@@ -0,0 +1,39 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+func TestAtomicWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	if err := utils.AtomicWriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatalf("AtomicWriteFile failed: %v", err)
+	}
+	if err := utils.AtomicWriteFile(path, []byte("new"), 0o600); err != nil {
+		t.Fatalf("AtomicWriteFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("ReadFile = %q, err: %v", data, err)
+	}
+	if runtime.GOOS != "windows" {
+		fi, _ := os.Stat(path)
+		if fi.Mode().Perm() != 0o600 {
+			t.Errorf("permissions = %v; want 0600", fi.Mode().Perm())
+		}
+	}
+
+	// no temp files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the written file, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrK8sSecretName = errors.New("secret name is required")
+
+	// k8sSecretKey is the set of characters allowed in Secret data keys.
+	k8sSecretKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+// K8sSecretOptions sets metadata and the data field of a Kubernetes Secret manifest.
+type K8sSecretOptions struct {
+	Name      string
+	Namespace string
+	// StringData puts plain values in stringData instead of base64 encoded values in data.
+	StringData bool
+}
+
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+type k8sMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// ToK8sSecret builds an Opaque v1/Secret YAML manifest from entities.
+// Key names must be valid Secret keys (alphanumeric, '-', '_' or '.').
+func ToK8sSecret(entities []models.Entity, opts K8sSecretOptions) (string, error) {
+	if opts.Name == "" {
+		return "", ErrK8sSecretName
+	}
+
+	values := make(map[string]string, len(entities))
+	for _, e := range entities {
+		if !k8sSecretKey.MatchString(e.Key) {
+			return "", fmt.Errorf("key '%s' is not a valid secret key, only alphanumeric characters, '-', '_' and '.' are allowed", e.Key)
+		}
+		if opts.StringData {
+			values[e.Key] = e.Value
+		} else {
+			values[e.Key] = base64.StdEncoding.EncodeToString([]byte(e.Value))
+		}
+	}
+
+	s := k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: opts.Name, Namespace: opts.Namespace},
+		Type:       "Opaque",
+	}
+	if opts.StringData {
+		s.StringData = values
+	} else {
+		s.Data = values
+	}
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
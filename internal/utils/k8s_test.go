@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+func TestToK8sSecret(t *testing.T) {
+	entities := []models.Entity{
+		{Key: "db.password", Value: "p@ss"},
+		{Key: "API_TOKEN", Value: "line1\nline2"},
+	}
+
+	got, err := utils.ToK8sSecret(entities, utils.K8sSecretOptions{Name: "app-secrets", Namespace: "prod"})
+	if err != nil {
+		t.Fatalf("ToK8sSecret failed: %v", err)
+	}
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secrets
+  namespace: prod
+type: Opaque
+data:
+  API_TOKEN: bGluZTEKbGluZTI=
+  db.password: cEBzcw==
+`
+	if got != want {
+		t.Errorf("ToK8sSecret() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToK8sSecretStringData(t *testing.T) {
+	entities := []models.Entity{{Key: "token", Value: "abc"}}
+
+	got, err := utils.ToK8sSecret(entities, utils.K8sSecretOptions{Name: "app", StringData: true})
+	if err != nil {
+		t.Fatalf("ToK8sSecret failed: %v", err)
+	}
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+type: Opaque
+stringData:
+  token: abc
+`
+	if got != want {
+		t.Errorf("ToK8sSecret() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToK8sSecretErrors(t *testing.T) {
+	if _, err := utils.ToK8sSecret(nil, utils.K8sSecretOptions{}); !errors.Is(err, utils.ErrK8sSecretName) {
+		t.Errorf("ToK8sSecret() without name error = %v, want %v", err, utils.ErrK8sSecretName)
+	}
+	entities := []models.Entity{{Key: "/prod/db", Value: "x"}}
+	if _, err := utils.ToK8sSecret(entities, utils.K8sSecretOptions{Name: "app"}); err == nil {
+		t.Error("ToK8sSecret() with invalid key should fail")
+	}
+}
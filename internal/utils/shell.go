@@ -0,0 +1,136 @@
+// Provides shell formatting helpers that set environment variables.
+//
+// Supported shells:
+//   - ShellPOSIX:      export KEY='value' (sh, bash, zsh)
+//   - ShellFish:       set -gx KEY 'value'
+//   - ShellPowerShell: $env:KEY = 'value'
+//   - ShellSystemd:    KEY="value" (systemd EnvironmentFile)
+//
+// Keys are normalized and sorted like in the dotenv output. A key that doesn't become
+// a valid variable name, or two keys that become the same name, return an error.
+// Values are quoted so they are assigned verbatim, including newlines.
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/yousysadmin/kv/internal/models"
+)
+
+// ShellMode defines the shell syntax of the output.
+type ShellMode string
+
+const (
+	ShellPOSIX      ShellMode = "shell"
+	ShellFish       ShellMode = "fish"
+	ShellPowerShell ShellMode = "powershell"
+	ShellSystemd    ShellMode = "systemd"
+)
+
+var (
+	ErrInvalidVariableName = errors.New("key is not a valid environment variable name")
+	ErrVariableCollision   = errors.New("keys map to the same environment variable name")
+)
+
+// ToShell builds output that sets environment variables in the given shell.
+// If withValues is false, variables are set to empty values.
+func ToShell(entities []models.Entity, withValues bool, mode ShellMode) (string, error) {
+	var line func(k, v string) string
+	switch mode {
+	case ShellPOSIX:
+		line = func(k, v string) string { return fmt.Sprintf("export %s=%s\n", k, QuotePOSIX(v)) }
+	case ShellFish:
+		line = func(k, v string) string { return fmt.Sprintf("set -gx %s %s\n", k, QuoteFish(v)) }
+	case ShellPowerShell:
+		line = func(k, v string) string { return fmt.Sprintf("$env:%s = %s\n", k, QuotePowerShell(v)) }
+	case ShellSystemd:
+		line = func(k, v string) string { return fmt.Sprintf("%s=%s\n", k, QuoteSystemd(v)) }
+	default:
+		return "", fmt.Errorf("unknown shell %q", mode)
+	}
+
+	vars, err := shellVars(entities, withValues)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		b.WriteString(line(k, vars[k]))
+	}
+	return b.String(), nil
+}
+
+// shellVars returns values by normalized variable name. Names must be identifiers
+// (a letter or _ followed by letters, digits and _) and unique.
+func shellVars(entities []models.Entity, withValues bool) (map[string]string, error) {
+	vars := make(map[string]string, len(entities))
+	keys := make(map[string]string, len(entities))
+	for _, e := range entities {
+		name := NormalizeKey(e.Key)
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariableName, e.Key)
+		}
+		if other, ok := keys[name]; ok {
+			return nil, fmt.Errorf("%w: %q and %q are both %s", ErrVariableCollision, other, e.Key, name)
+		}
+		keys[name] = e.Key
+		if withValues {
+			vars[name] = e.Value
+		} else {
+			vars[name] = ""
+		}
+	}
+	return vars, nil
+}
+
+// QuotePOSIX quotes a value for a POSIX shell.
+// Nothing is special inside single quotes, a single quote ends the quoting, is escaped and quoting restarts.
+func QuotePOSIX(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// QuoteFish quotes a value for fish, only \ and ' are escaped inside single quotes.
+func QuoteFish(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// QuotePowerShell quotes a value for PowerShell, single quotes are escaped by doubling.
+// PowerShell also treats typographic single quotes as quote characters.
+func QuotePowerShell(v string) string {
+	var b strings.Builder
+	b.Grow(len(v) + 2)
+	b.WriteByte('\'')
+	for _, r := range v {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// QuoteSystemd quotes a value for a systemd EnvironmentFile.
+// Inside double quotes \, ", ` and $ are escaped with a backslash, newlines are kept.
+func QuoteSystemd(v string) string {
+	v = strings.ReplaceAll(v, "\r\n", "\n")
+	var b strings.Builder
+	b.Grow(len(v) + 2)
+	b.WriteByte('"')
+	for _, r := range v {
+		switch r {
+		case '\\', '"', '`', '$':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
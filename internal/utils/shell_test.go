@@ -0,0 +1,222 @@
+package utils_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+)
+
+var shellEntities = []models.Entity{
+	{Key: "plain", Value: "value"},
+	{Key: "EMPTY", Value: ""},
+	{Key: "single-quote", Value: `it's 'quoted'`},
+	{Key: "DOUBLE_QUOTE", Value: `say "hi"`},
+	{Key: "BACKSLASH", Value: `C:\path\n\'`},
+	{Key: "VARS", Value: "$HOME `id` $(id) %PATH% ${x}"},
+	{Key: "TYPOGRAPHIC", Value: "it’s ‘x’"},
+	{Key: "PEM", Value: "-----BEGIN KEY-----\nabc\ndef\n-----END KEY-----"},
+}
+
+var shellWant = map[string]string{
+	"PLAIN":        "value",
+	"EMPTY":        "",
+	"SINGLE_QUOTE": `it's 'quoted'`,
+	"DOUBLE_QUOTE": `say "hi"`,
+	"BACKSLASH":    `C:\path\n\'`,
+	"VARS":         "$HOME `id` $(id) %PATH% ${x}",
+	"TYPOGRAPHIC":  "it’s ‘x’",
+	"PEM":          "-----BEGIN KEY-----\nabc\ndef\n-----END KEY-----",
+}
+
+func TestToShell(t *testing.T) {
+	entities := []models.Entity{{Key: "db.host", Value: "it's"}, {Key: "a", Value: "1"}}
+	tests := []struct {
+		mode utils.ShellMode
+		want string
+	}{
+		{utils.ShellPOSIX, "export A='1'\nexport DB_HOST='it'\\''s'\n"},
+		{utils.ShellFish, "set -gx A '1'\nset -gx DB_HOST 'it\\'s'\n"},
+		{utils.ShellPowerShell, "$env:A = '1'\n$env:DB_HOST = 'it''s'\n"},
+		{utils.ShellSystemd, "A=\"1\"\nDB_HOST=\"it's\"\n"},
+	}
+	for _, tt := range tests {
+		got, err := utils.ToShell(entities, true, tt.mode)
+		if err != nil {
+			t.Fatalf("ToShell(%s) failed: %v", tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("ToShell(%s) = %q; want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := utils.ToShell(entities, true, "csh"); err == nil {
+		t.Error("ToShell(csh) should fail")
+	}
+}
+
+func TestToShellInvalidNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities []models.Entity
+		want     error
+	}{
+		{"leading digit", []models.Entity{{Key: "1TOKEN", Value: "x"}}, utils.ErrInvalidVariableName},
+		{"no name", []models.Entity{{Key: "---", Value: "x"}}, utils.ErrInvalidVariableName},
+		{"collision", []models.Entity{{Key: "a-b", Value: "1"}, {Key: "a_b", Value: "2"}}, utils.ErrVariableCollision},
+		{"case collision", []models.Entity{{Key: "token", Value: "1"}, {Key: "TOKEN", Value: "1"}}, utils.ErrVariableCollision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []utils.ShellMode{utils.ShellPOSIX, utils.ShellFish, utils.ShellPowerShell, utils.ShellSystemd} {
+				if _, err := utils.ToShell(tt.entities, true, mode); !errors.Is(err, tt.want) {
+					t.Errorf("ToShell(%s) error = %v; want %v", mode, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestToShellWithoutValues(t *testing.T) {
+	got, err := utils.ToShell(shellEntities[:1], false, utils.ShellPOSIX)
+	if err != nil {
+		t.Fatalf("ToShell failed: %v", err)
+	}
+	if want := "export PLAIN=''\n"; got != want {
+		t.Errorf("ToShell() = %q; want %q", got, want)
+	}
+}
+
+func TestToShellRoundTrip(t *testing.T) {
+	for _, mode := range []utils.ShellMode{utils.ShellPOSIX, utils.ShellFish, utils.ShellPowerShell, utils.ShellSystemd} {
+		out, err := utils.ToShell(shellEntities, true, mode)
+		if err != nil {
+			t.Fatalf("ToShell(%s) failed: %v", mode, err)
+		}
+		got, err := parseShell(out, mode)
+		if err != nil {
+			t.Fatalf("parse %s output failed: %v\n%s", mode, err, out)
+		}
+		if !reflect.DeepEqual(got, shellWant) {
+			t.Errorf("round trip %s = %#v; want %#v", mode, got, shellWant)
+		}
+	}
+}
+
+// TestToShellPOSIXSourced sources the output in sh and compares the exported variables.
+func TestToShellPOSIXSourced(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	out, err := utils.ToShell(shellEntities, true, utils.ShellPOSIX)
+	if err != nil {
+		t.Fatalf("ToShell failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "env.sh")
+	if err := os.WriteFile(path, []byte(out), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range shellWant {
+		cmd := exec.Command(sh, "-c", `. "$1" && printf '%s' "$(printenv "$2"; echo x)"`, "sh", path, k)
+		cmd.Env = []string{}
+		got, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("sh failed: %v", err)
+		}
+		// printenv adds a newline, the x guards trailing newlines from command substitution
+		if v := strings.TrimSuffix(string(got), "\nx"); v != want {
+			t.Errorf("%s = %q; want %q", k, v, want)
+		}
+	}
+}
+
+// parseShell reads variables from ToShell output following the quoting rules of each shell.
+func parseShell(out string, mode utils.ShellMode) (map[string]string, error) {
+	syntax := map[utils.ShellMode]struct{ prefix, sep, quote string }{
+		utils.ShellPOSIX:      {"export ", "=", "'"},
+		utils.ShellFish:       {"set -gx ", " ", "'"},
+		utils.ShellPowerShell: {"$env:", " = ", "'"},
+		utils.ShellSystemd:    {"", "=", `"`},
+	}
+	prefix, sep, quote := syntax[mode].prefix, syntax[mode].sep, syntax[mode].quote
+
+	vars := map[string]string{}
+	for out != "" {
+		rest, ok := strings.CutPrefix(out, prefix)
+		if !ok {
+			return nil, errors.New("missing prefix")
+		}
+		k, rest, ok := strings.Cut(rest, sep)
+		if !ok {
+			return nil, errors.New("missing separator")
+		}
+		if !strings.HasPrefix(rest, quote) {
+			return nil, errors.New("value is not quoted")
+		}
+
+		var v strings.Builder
+		i := 1
+	value:
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			switch {
+			case mode == utils.ShellPOSIX && strings.HasPrefix(rest[i:], `'\''`):
+				v.WriteByte('\'')
+				i += 3
+			case mode == utils.ShellFish && c == '\\' && i+1 < len(rest) && strings.ContainsRune(`\'`, rune(rest[i+1])):
+				i++
+				v.WriteByte(rest[i])
+			case mode == utils.ShellSystemd && c == '\\' && i+1 < len(rest) && strings.ContainsRune("\\\"`$", rune(rest[i+1])):
+				i++
+				v.WriteByte(rest[i])
+			case mode == utils.ShellPowerShell && isPowerShellQuote(rest[i:]):
+				q := quoteLen(rest[i:])
+				if isPowerShellQuote(rest[i+q:]) {
+					v.WriteString(rest[i : i+q])
+					i += q + quoteLen(rest[i+q:]) - 1
+					continue
+				}
+				if rest[i] != '\'' {
+					return nil, errors.New("unexpected typographic quote")
+				}
+				break value
+			case string(c) == quote:
+				break value
+			default:
+				v.WriteByte(c)
+			}
+		}
+		if i >= len(rest) {
+			return nil, errors.New("unterminated value")
+		}
+		vars[k] = v.String()
+
+		if out, ok = strings.CutPrefix(rest[i+1:], "\n"); !ok {
+			return nil, errors.New("missing newline")
+		}
+	}
+	return vars, nil
+}
+
+// isPowerShellQuote reports whether s starts with a character PowerShell treats as a single quote.
+func isPowerShellQuote(s string) bool {
+	return quoteLen(s) > 0
+}
+
+// quoteLen returns the byte length of the single quote character at the start of s, or 0.
+func quoteLen(s string) int {
+	for _, q := range []string{"'", "‘", "’", "‚", "‛"} {
+		if strings.HasPrefix(s, q) {
+			return len(q)
+		}
+	}
+	return 0
+}
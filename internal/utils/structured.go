@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"github.com/pelletier/go-toml/v2"
+	"github.com/yousysadmin/kv/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ToYAML builds a YAML mapping of key names to values, sorted by key.
+// If withValues is false, values are empty.
+func ToYAML(entities []models.Entity, withValues bool) (string, error) {
+	out, err := yaml.Marshal(valueMap(entities, withValues))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ToTOML builds a TOML table of key names to values, sorted by key.
+// If withValues is false, values are empty.
+func ToTOML(entities []models.Entity, withValues bool) (string, error) {
+	out, err := toml.Marshal(valueMap(entities, withValues))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// valueMap returns values by key name, keys are not normalized.
+func valueMap(entities []models.Entity, withValues bool) map[string]string {
+	m := make(map[string]string, len(entities))
+	for _, e := range entities {
+		if withValues {
+			m[e.Key] = e.Value
+		} else {
+			m[e.Key] = ""
+		}
+	}
+	return m
+}
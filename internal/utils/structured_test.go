@@ -0,0 +1,77 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/yousysadmin/kv/internal/models"
+	"github.com/yousysadmin/kv/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+var structuredEntities = []models.Entity{
+	{Key: "plain", Value: "value"},
+	{Key: "db.host", Value: "localhost"},
+	{Key: "/prod/api-token", Value: `it's "quoted" \n`},
+	{Key: "empty", Value: ""},
+	{Key: "number", Value: "0123"},
+	{Key: "bool", Value: "yes"},
+	{Key: "pem", Value: "-----BEGIN KEY-----\nabc\r\ndef\n-----END KEY-----\n"},
+}
+
+func structuredWant() map[string]string {
+	want := make(map[string]string, len(structuredEntities))
+	for _, e := range structuredEntities {
+		want[e.Key] = e.Value
+	}
+	return want
+}
+
+func TestToYAML(t *testing.T) {
+	got, err := utils.ToYAML(structuredEntities[:2], true)
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if want := "db.host: localhost\nplain: value\n"; got != want {
+		t.Errorf("ToYAML() = %q; want %q", got, want)
+	}
+}
+
+func TestToTOML(t *testing.T) {
+	got, err := utils.ToTOML(structuredEntities[:2], true)
+	if err != nil {
+		t.Fatalf("ToTOML failed: %v", err)
+	}
+	if want := "'db.host' = 'localhost'\nplain = 'value'\n"; got != want {
+		t.Errorf("ToTOML() = %q; want %q", got, want)
+	}
+}
+
+func TestToYAMLRoundTrip(t *testing.T) {
+	out, err := utils.ToYAML(structuredEntities, true)
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	var got map[string]string
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("yaml.Unmarshal failed: %v\n%s", err, out)
+	}
+	if want := structuredWant(); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %#v; want %#v", got, want)
+	}
+}
+
+func TestToTOMLRoundTrip(t *testing.T) {
+	out, err := utils.ToTOML(structuredEntities, true)
+	if err != nil {
+		t.Fatalf("ToTOML failed: %v", err)
+	}
+	var got map[string]string
+	if err := toml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("toml.Unmarshal failed: %v\n%s", err, out)
+	}
+	if want := structuredWant(); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %#v; want %#v", got, want)
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseTags parses tags in key=value form, a tag without a value is stored with an empty value.
+func ParseTags(input []string) (map[string]string, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(input))
+	for _, t := range input {
+		k, v, _ := strings.Cut(t, "=")
+		k = strings.TrimSpace(k)
+		if k == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", t)
+		}
+		tags[k] = strings.TrimSpace(v)
+	}
+	return tags, nil
+}
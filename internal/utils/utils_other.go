@@ -0,0 +1,8 @@
+//go:build !unix
+
+package utils
+
+// IsOnNetworkStorage always reports false, network filesystems are not detected on this platform.
+func IsOnNetworkStorage(path string) (bool, error) {
+	return false, nil
+}
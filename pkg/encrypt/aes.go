@@ -5,6 +5,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -14,7 +15,9 @@ import (
 // AES holds the key and data for encryption or decryption.
 type AES struct {
 	key  string
+	raw  []byte
 	data string
+	ad   []byte
 }
 
 // NewAES creates a new AES instance with the provided key and data.
@@ -25,6 +28,23 @@ func NewAES(key, data string) *AES {
 	}
 }
 
+// NewAESWithRawKey creates a new AES instance with raw key bytes and data.
+// The key is used in place and is not copied, so it can be kept in memory the caller wipes.
+func NewAESWithRawKey(key []byte, data string) *AES {
+	return &AES{
+		raw:  key,
+		data: data,
+	}
+}
+
+// WithAdditionalData sets additional data that is authenticated, but not encrypted.
+// Ciphertext produced with additional data uses the v2 format (PrefixV2) and can be
+// decrypted only with the same additional data.
+func (a *AES) WithAdditionalData(ad []byte) *AES {
+	a.ad = ad
+	return a
+}
+
 // AESKeySize represents the size of an AES key in bytes.
 type AESKeySize int
 
@@ -44,6 +64,21 @@ const (
 	PrefixAES192 string = "aes192:"
 	// PrefixAES256 is the prefix used to identify AES-256 encrypted strings.
 	PrefixAES256 string = "aes256:"
+	// PrefixV2 is the format version prefix of strings encrypted with additional data,
+	// it precedes the key size prefix (e.g. "v2:aes256:").
+	PrefixV2 string = "v2:"
+)
+
+// IsV2 reports whether the encrypted string uses the v2 format (authenticated additional data).
+func IsV2(data string) bool {
+	return strings.HasPrefix(data, PrefixV2)
+}
+
+const (
+	// KeyPrefixBase64 is the prefix of keys encoded with base64.
+	KeyPrefixBase64 string = "b64:"
+	// KeyPrefixHex is the prefix of keys encoded with hex.
+	KeyPrefixHex string = "hex:"
 )
 
 // Prefix returns the prefix associated with the AES key size.
@@ -78,11 +113,12 @@ var (
 
 // Encrypt encrypts plaintext using AES-GCM and returns a base64-encoded ciphertext with prefix.
 func (a *AES) Encrypt() (string, error) {
-	if err := ValidateAESKey(a.key); err != nil {
+	key, err := a.rawKey()
+	if err != nil {
 		return "", err
 	}
 
-	block, err := aes.NewCipher([]byte(a.key))
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", ErrorInvalidKey, err)
 	}
@@ -97,22 +133,37 @@ func (a *AES) Encrypt() (string, error) {
 		return "", fmt.Errorf("%w: %v", ErrorNonceReadFailed, err)
 	}
 
-	ciphertext := aesGCM.Seal(nonce, nonce, []byte(a.data), nil)
-	prefix := fmt.Sprintf("aes%d:", len(a.key)*8)
+	ciphertext := aesGCM.Seal(nonce, nonce, []byte(a.data), a.ad)
+	prefix := fmt.Sprintf("aes%d:", len(key)*8)
+	if a.ad != nil {
+		prefix = PrefixV2 + prefix
+	}
 	return prefix + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
 // Decrypt decrypts a base64-encoded AES-GCM ciphertext string.
+// The v2 format is authenticated with the additional data set by WithAdditionalData,
+// older strings are decrypted without it.
 func (a *AES) Decrypt() (string, error) {
-	if err := ValidateAESKey(a.key); err != nil {
+	key, err := a.rawKey()
+	if err != nil {
 		return "", err
 	}
 
-	prefix, err := AESKeySize(len(a.key)).Prefix()
+	prefix, err := AESKeySize(len(key)).Prefix()
 	if err != nil {
 		return "", err
 	}
 
+	var ad []byte
+	if IsV2(a.data) {
+		ad = a.ad
+		a.data = a.data[len(PrefixV2):]
+		if !strings.HasPrefix(a.data, prefix) {
+			return "", fmt.Errorf("%w: expected prefix %q not found", ErrorInvalidKey, prefix)
+		}
+	}
+
 	if strings.HasPrefix(a.data, PrefixAES128) ||
 		strings.HasPrefix(a.data, PrefixAES192) ||
 		strings.HasPrefix(a.data, PrefixAES256) {
@@ -127,7 +178,7 @@ func (a *AES) Decrypt() (string, error) {
 		return "", fmt.Errorf("%w: %v", ErrorBase64Decode, err)
 	}
 
-	block, err := aes.NewCipher([]byte(a.key))
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", ErrorInvalidKey, err)
 	}
@@ -142,7 +193,7 @@ func (a *AES) Decrypt() (string, error) {
 	}
 
 	nonce, ciphertext := ciphertext[:aesGCM.NonceSize()], ciphertext[aesGCM.NonceSize():]
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, ad)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", ErrorDecryptionFailed, err)
 	}
@@ -150,31 +201,69 @@ func (a *AES) Decrypt() (string, error) {
 	return string(plaintext), nil
 }
 
-// GenerateRandomAESKey generates a random AES key of the given bit length.
+// rawKey returns the raw key bytes, decoding the key string if the raw key is not set.
+func (a *AES) rawKey() ([]byte, error) {
+	if a.raw == nil {
+		return DecodeAESKey(a.key)
+	}
+	if !AESKeySize(len(a.raw)).IsValid() {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrorInvalidKeyLength, len(a.raw))
+	}
+	return a.raw, nil
+}
+
+// GenerateRandomAESKey generates a random AES key of the given size.
+// The key is encoded as "b64:" followed by the base64 encoded random bytes.
 func GenerateRandomAESKey(bits AESKeySize) (string, error) {
 	if !bits.IsValid() {
 		return "", fmt.Errorf("%w: %d", ErrorInvalidKeyLength, bits)
 	}
-	keyLen := int(bits)
 
-	rawKey := make([]byte, keyLen)
+	rawKey := make([]byte, int(bits))
 	if _, err := io.ReadFull(rand.Reader, rawKey); err != nil {
 		return "", fmt.Errorf("%w: %v", ErrorKeyGenerationFailed, err)
 	}
+	return EncodeAESKey(rawKey), nil
+}
 
-	b64str := base64.StdEncoding.EncodeToString(rawKey)
-	b64str = strings.TrimRight(b64str, "=")
+// EncodeAESKey encodes raw key bytes as a "b64:" prefixed string.
+func EncodeAESKey(raw []byte) string {
+	return KeyPrefixBase64 + base64.StdEncoding.EncodeToString(raw)
+}
 
-	if len(b64str) > keyLen {
-		b64str = b64str[:keyLen]
+// DecodeAESKey returns the raw bytes of an encoded key and checks the key length.
+// Keys prefixed with "b64:" or "hex:" are decoded, any other key is a legacy
+// printable key and its characters are used as the key bytes.
+func DecodeAESKey(key string) ([]byte, error) {
+	var (
+		raw []byte
+		err error
+	)
+	switch {
+	case strings.HasPrefix(key, KeyPrefixBase64):
+		raw, err = base64.StdEncoding.DecodeString(key[len(KeyPrefixBase64):])
+	case strings.HasPrefix(key, KeyPrefixHex):
+		raw, err = hex.DecodeString(key[len(KeyPrefixHex):])
+	default:
+		raw = []byte(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidKey, err)
 	}
-	return b64str, nil
+	if !AESKeySize(len(raw)).IsValid() {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrorInvalidKeyLength, len(raw))
+	}
+	return raw, nil
+}
+
+// IsLegacyAESKey reports whether the key is a legacy printable key without encoding prefix.
+// Such keys have less entropy than their size, e.g. a 32 character key carries about 192 bits.
+func IsLegacyAESKey(key string) bool {
+	return !strings.HasPrefix(key, KeyPrefixBase64) && !strings.HasPrefix(key, KeyPrefixHex)
 }
 
 // ValidateAESKey checks if the key length is valid for AES.
 func ValidateAESKey(key string) error {
-	if AESKeySize(len(key)).IsValid() {
-		return nil
-	}
-	return fmt.Errorf("%w: got %d chars", ErrorInvalidKeyLength, len(key))
+	_, err := DecodeAESKey(key)
+	return err
 }
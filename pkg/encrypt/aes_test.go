@@ -1,6 +1,7 @@
 package encrypt_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -14,8 +15,16 @@ func TestGenerateRandomAESKey(t *testing.T) {
 			t.Errorf("GenerateRandomAESKey failed for size %d: %v", size, err)
 			continue
 		}
-		if len(key) != int(size) {
-			t.Errorf("Expected key length %d, got %d", size, len(key))
+		if !strings.HasPrefix(key, encrypt.KeyPrefixBase64) || encrypt.IsLegacyAESKey(key) {
+			t.Errorf("Expected %q prefixed key, got %q", encrypt.KeyPrefixBase64, key)
+		}
+		raw, err := encrypt.DecodeAESKey(key)
+		if err != nil {
+			t.Errorf("DecodeAESKey failed for size %d: %v", size, err)
+			continue
+		}
+		if len(raw) != int(size) {
+			t.Errorf("Expected key length %d, got %d", size, len(raw))
 		}
 	}
 }
@@ -43,6 +52,82 @@ func TestValidateAESKey(t *testing.T) {
 	}
 }
 
+func TestDecodeAESKey(t *testing.T) {
+	raw := make([]byte, 32)
+	for i := range raw {
+		raw[i] = byte(i * 7)
+	}
+
+	cases := map[string]int{
+		encrypt.EncodeAESKey(raw):                       32,
+		encrypt.KeyPrefixHex + strings.Repeat("ab", 16): 16,
+		strings.Repeat("c", 24):                         24, // legacy printable key
+	}
+	for key, size := range cases {
+		got, err := encrypt.DecodeAESKey(key)
+		if err != nil {
+			t.Errorf("DecodeAESKey(%q) error = %v", key, err)
+			continue
+		}
+		if len(got) != size {
+			t.Errorf("DecodeAESKey(%q) length = %d; want %d", key, len(got), size)
+		}
+	}
+
+	for _, key := range []string{
+		encrypt.KeyPrefixBase64 + "not base64!",
+		encrypt.KeyPrefixHex + "zz",
+		encrypt.EncodeAESKey(raw[:20]),
+	} {
+		if _, err := encrypt.DecodeAESKey(key); err == nil {
+			t.Errorf("DecodeAESKey(%q) = nil error; want error", key)
+		}
+	}
+}
+
+func TestLegacyAndEncodedKeysEncrypt(t *testing.T) {
+	legacy := strings.Repeat("k", 32)
+	encoded := encrypt.EncodeAESKey([]byte(legacy))
+
+	// same key bytes in both encodings produce interchangeable ciphertexts
+	ciphertext, err := encrypt.NewAES(legacy, "value").Encrypt()
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	got, err := encrypt.NewAES(encoded, ciphertext).Decrypt()
+	if err != nil || got != "value" {
+		t.Fatalf("Decrypt with encoded key = %q, err: %v", got, err)
+	}
+	if !encrypt.IsLegacyAESKey(legacy) || encrypt.IsLegacyAESKey(encoded) {
+		t.Error("IsLegacyAESKey mismatch")
+	}
+}
+
+func TestRawKey(t *testing.T) {
+	key, _ := encrypt.GenerateRandomAESKey(encrypt.AES256)
+	raw, err := encrypt.DecodeAESKey(key)
+	if err != nil {
+		t.Fatalf("DecodeAESKey failed: %v", err)
+	}
+
+	// raw and encoded keys produce interchangeable ciphertexts
+	ciphertext, err := encrypt.NewAESWithRawKey(raw, "value").WithAdditionalData([]byte("ad")).Encrypt()
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	got, err := encrypt.NewAES(key, ciphertext).WithAdditionalData([]byte("ad")).Decrypt()
+	if err != nil || got != "value" {
+		t.Fatalf("Decrypt with encoded key = %q, err: %v", got, err)
+	}
+	if got, err = encrypt.NewAESWithRawKey(raw, ciphertext).WithAdditionalData([]byte("ad")).Decrypt(); err != nil || got != "value" {
+		t.Fatalf("Decrypt with raw key = %q, err: %v", got, err)
+	}
+
+	if _, err := encrypt.NewAESWithRawKey(raw[:10], "value").Encrypt(); !errors.Is(err, encrypt.ErrorInvalidKeyLength) {
+		t.Errorf("Expected ErrorInvalidKeyLength, got: %v", err)
+	}
+}
+
 func TestEncryptDecryptAES(t *testing.T) {
 	key, _ := encrypt.GenerateRandomAESKey(encrypt.AES256)
 	plain := "Secret text to encrypt"
@@ -112,3 +197,44 @@ func BenchmarkDecryptAES(b *testing.B) {
 		}
 	}
 }
+
+func TestEncryptDecryptWithAdditionalData(t *testing.T) {
+	key, _ := encrypt.GenerateRandomAESKey(encrypt.AES256)
+	plain := "Secret text to encrypt"
+
+	ciphertext, err := encrypt.NewAES(key, plain).WithAdditionalData([]byte("prod/token")).Encrypt()
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !strings.HasPrefix(ciphertext, encrypt.PrefixV2+encrypt.PrefixAES256) || !encrypt.IsV2(ciphertext) {
+		t.Errorf("expected prefix %q, got %q", encrypt.PrefixV2+encrypt.PrefixAES256, ciphertext[:11])
+	}
+
+	decrypted, err := encrypt.NewAES(key, ciphertext).WithAdditionalData([]byte("prod/token")).Decrypt()
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if decrypted != plain {
+		t.Errorf("expected %q, got %q", plain, decrypted)
+	}
+
+	for _, ad := range [][]byte{nil, []byte("prod/password")} {
+		if _, err := encrypt.NewAES(key, ciphertext).WithAdditionalData(ad).Decrypt(); !errors.Is(err, encrypt.ErrorDecryptionFailed) {
+			t.Errorf("Decrypt with additional data %q: expected ErrorDecryptionFailed, got %v", ad, err)
+		}
+	}
+}
+
+func TestDecryptLegacyIgnoresAdditionalData(t *testing.T) {
+	key, _ := encrypt.GenerateRandomAESKey(encrypt.AES256)
+
+	ciphertext, _ := encrypt.NewAES(key, "legacy").Encrypt()
+	if encrypt.IsV2(ciphertext) {
+		t.Fatalf("expected legacy format without additional data, got %q", ciphertext)
+	}
+
+	decrypted, err := encrypt.NewAES(key, ciphertext).WithAdditionalData([]byte("prod/token")).Decrypt()
+	if err != nil || decrypted != "legacy" {
+		t.Fatalf("Decrypt legacy = %q, err: %v", decrypted, err)
+	}
+}
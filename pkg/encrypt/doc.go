@@ -15,10 +15,24 @@ Encrypt(plaintext, key) - Encrypts text and prepends an AES prefix (e.g. `aes256
 
 Decrypt(ciphertext, key) - Verifies prefix, decrypts ciphertext.
 
-GenerateRandomAESKey(bits) - Generates a random printable AES key of the desired size.
+GenerateRandomAESKey(bits) - Generates a random AES key of the desired size, encoded with the "b64:" prefix.
+
+DecodeAESKey(key) - Decodes a key to raw bytes and validates its length.
 
 ValidateAESKey(key) - Validates key length.
 
+# Key encoding
+
+Keys are strings with an encoding prefix, the raw key bytes are decoded before use:
+
+b64:<base64 of raw key bytes>
+
+hex:<hex of raw key bytes>
+
+A key without prefix is a legacy printable key, its characters are the key bytes.
+Legacy keys are still supported, but carry less entropy than their size
+(a 32 character base64 key has about 192 bits).
+
 # Prefixes
 
 All encrypted strings are prefixed with:
@@ -28,5 +42,13 @@ aes128:
 aes192:
 
 aes256:
+
+Strings encrypted with additional data (WithAdditionalData) use the v2 format
+and have the version prefix before the key size prefix:
+
+v2:aes256:
+
+The additional data is authenticated by AES-GCM, so a v2 string decrypts only with
+the same additional data. It's used to bind a value to the place it's stored at.
 */
 package encrypt
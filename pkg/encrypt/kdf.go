@@ -0,0 +1,75 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParams are scrypt cost parameters.
+type ScryptParams struct {
+	N int `yaml:"n" json:"n"`
+	R int `yaml:"r" json:"r"`
+	P int `yaml:"p" json:"p"`
+}
+
+// DefaultScryptParams are the recommended interactive scrypt parameters (about 100ms, 32MB).
+var DefaultScryptParams = ScryptParams{N: 1 << 15, R: 8, P: 1}
+
+// Upper bounds of scrypt parameters, parameters read from files are untrusted
+// and large values would make key derivation take unbounded memory and time.
+const (
+	MaxScryptN = 1 << 20
+	MaxScryptR = 32
+	MaxScryptP = 16
+)
+
+var (
+	ErrorEmptyPassphrase     = errors.New("passphrase is empty")
+	ErrorKeyDerivationFailed = errors.New("failed to derive key from passphrase")
+	ErrorInvalidScryptParams = errors.New("invalid scrypt parameters")
+)
+
+// Validate checks that N is a power of two greater than 1 and the parameters are within the bounds.
+func (p ScryptParams) Validate() error {
+	switch {
+	case p.N <= 1 || p.N&(p.N-1) != 0 || p.N > MaxScryptN:
+		return fmt.Errorf("%w: N must be a power of two between 2 and %d, got %d", ErrorInvalidScryptParams, MaxScryptN, p.N)
+	case p.R < 1 || p.R > MaxScryptR:
+		return fmt.Errorf("%w: r must be between 1 and %d, got %d", ErrorInvalidScryptParams, MaxScryptR, p.R)
+	case p.P < 1 || p.P > MaxScryptP:
+		return fmt.Errorf("%w: p must be between 1 and %d, got %d", ErrorInvalidScryptParams, MaxScryptP, p.P)
+	}
+	return nil
+}
+
+// NewSalt generates a random salt for key derivation.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorKeyGenerationFailed, err)
+	}
+	return salt, nil
+}
+
+// DeriveKeyScrypt derives an AES key of the given size from a passphrase using scrypt.
+// The parameters are validated first. The returned key can be used with NewAES.
+func DeriveKeyScrypt(passphrase, salt []byte, params ScryptParams, size AESKeySize) (string, error) {
+	if len(passphrase) == 0 {
+		return "", ErrorEmptyPassphrase
+	}
+	if !size.IsValid() {
+		return "", fmt.Errorf("%w: %d", ErrorInvalidKeyLength, size)
+	}
+	if err := params.Validate(); err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, int(size))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrorKeyDerivationFailed, err)
+	}
+	return EncodeAESKey(key), nil
+}
@@ -0,0 +1,79 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+// fast parameters for tests
+var testScryptParams = encrypt.ScryptParams{N: 1 << 10, R: 8, P: 1}
+
+func TestDeriveKeyScrypt(t *testing.T) {
+	salt, err := encrypt.NewSalt()
+	if err != nil {
+		t.Fatalf("NewSalt failed: %v", err)
+	}
+
+	k1, err := encrypt.DeriveKeyScrypt([]byte("passphrase"), salt, testScryptParams, encrypt.AES256)
+	if err != nil {
+		t.Fatalf("DeriveKeyScrypt failed: %v", err)
+	}
+	if err := encrypt.ValidateAESKey(k1); err != nil {
+		t.Fatalf("derived key is invalid: %v", err)
+	}
+
+	k2, _ := encrypt.DeriveKeyScrypt([]byte("passphrase"), salt, testScryptParams, encrypt.AES256)
+	if k1 != k2 {
+		t.Error("expected the same key for the same passphrase and salt")
+	}
+
+	other, _ := encrypt.NewSalt()
+	if bytes.Equal(salt, other) {
+		t.Fatal("expected different salts")
+	}
+	k3, _ := encrypt.DeriveKeyScrypt([]byte("passphrase"), other, testScryptParams, encrypt.AES256)
+	if k1 == k3 {
+		t.Error("expected a different key for a different salt")
+	}
+}
+
+func TestDeriveKeyScryptEmptyPassphrase(t *testing.T) {
+	if _, err := encrypt.DeriveKeyScrypt(nil, []byte("salt"), testScryptParams, encrypt.AES256); err == nil {
+		t.Error("expected error for empty passphrase")
+	}
+}
+
+func TestScryptParamsValidate(t *testing.T) {
+	valid := []encrypt.ScryptParams{
+		encrypt.DefaultScryptParams,
+		testScryptParams,
+		{N: encrypt.MaxScryptN, R: encrypt.MaxScryptR, P: encrypt.MaxScryptP},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v; want nil", p, err)
+		}
+	}
+
+	invalid := []encrypt.ScryptParams{
+		{},
+		{N: 1, R: 8, P: 1},
+		{N: 1000, R: 8, P: 1},
+		{N: encrypt.MaxScryptN << 1, R: 8, P: 1},
+		{N: 1 << 10, R: 0, P: 1},
+		{N: 1 << 10, R: encrypt.MaxScryptR + 1, P: 1},
+		{N: 1 << 10, R: 8, P: 0},
+		{N: 1 << 10, R: 8, P: encrypt.MaxScryptP + 1},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, encrypt.ErrorInvalidScryptParams) {
+			t.Errorf("Validate(%+v) = %v; want ErrorInvalidScryptParams", p, err)
+		}
+		if _, err := encrypt.DeriveKeyScrypt([]byte("passphrase"), []byte("salt"), p, encrypt.AES256); !errors.Is(err, encrypt.ErrorInvalidScryptParams) {
+			t.Errorf("DeriveKeyScrypt(%+v) = %v; want ErrorInvalidScryptParams", p, err)
+		}
+	}
+}
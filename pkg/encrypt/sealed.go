@@ -0,0 +1,68 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KDFScrypt is the key derivation function of a SealedBox.
+const KDFScrypt = "scrypt"
+
+var (
+	ErrorWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// SealedBox is data sealed with a passphrase: the data encrypted with AES-256-GCM
+// under a key derived from the passphrase with scrypt.
+type SealedBox struct {
+	KDF    string       `yaml:"kdf" json:"kdf"`
+	Params ScryptParams `yaml:"params" json:"params"`
+	Salt   string       `yaml:"salt" json:"salt"`
+	Data   string       `yaml:"data" json:"data"`
+}
+
+// Seal encrypts plain with a key derived from passphrase and a fresh salt.
+func Seal(passphrase, plain []byte, params ScryptParams) (*SealedBox, error) {
+	salt, err := NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveKeyScrypt(passphrase, salt, params, AES256)
+	if err != nil {
+		return nil, err
+	}
+	data, err := NewAES(key, string(plain)).Encrypt()
+	if err != nil {
+		return nil, err
+	}
+	return &SealedBox{
+		KDF:    KDFScrypt,
+		Params: params,
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		Data:   data,
+	}, nil
+}
+
+// Open decrypts the sealed data with passphrase, a wrong passphrase returns ErrorWrongPassphrase.
+func (b *SealedBox) Open(passphrase []byte) ([]byte, error) {
+	if b.KDF != KDFScrypt {
+		return nil, fmt.Errorf("unsupported key derivation function %q", b.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(b.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decode salt: %w", err)
+	}
+	key, err := DeriveKeyScrypt(passphrase, salt, b.Params, AES256)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := NewAES(key, b.Data).Decrypt()
+	if errors.Is(err, ErrorDecryptionFailed) {
+		return nil, ErrorWrongPassphrase
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(plain), nil
+}
@@ -0,0 +1,46 @@
+package encrypt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/yousysadmin/kv/pkg/encrypt"
+)
+
+func TestSealedBox(t *testing.T) {
+	box, err := encrypt.Seal([]byte("passphrase"), []byte("secret data"), testScryptParams)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if box.KDF != encrypt.KDFScrypt || box.Params != testScryptParams {
+		t.Errorf("unexpected box header: %+v", box)
+	}
+
+	// the box round-trips through its serialized form
+	data, err := json.Marshal(box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got encrypt.SealedBox
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := got.Open([]byte("passphrase"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if string(plain) != "secret data" {
+		t.Errorf("Open() = %q, want %q", plain, "secret data")
+	}
+
+	if _, err := got.Open([]byte("wrong")); !errors.Is(err, encrypt.ErrorWrongPassphrase) {
+		t.Errorf("Open with wrong passphrase: expected ErrorWrongPassphrase, got: %v", err)
+	}
+
+	got.KDF = "argon2"
+	if _, err := got.Open([]byte("passphrase")); err == nil {
+		t.Error("expected an error for an unsupported KDF")
+	}
+}